@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"../gamethrive"
+	"../gamethrive/locale"
+)
+
+var (
+	AudienceFlagSet          *flag.FlagSet
+	AudienceNotificationFlag *string
+	AudiencePlayersFlag      *string
+	AudienceSegmentsFlag     *string
+	AudienceAuthFlag         *string
+	AudienceListFlag         *bool
+)
+
+func init() {
+	AudienceFlagSet = flag.NewFlagSet("notification audience", flag.ContinueOnError)
+	AudienceNotificationFlag = AudienceFlagSet.String("json", "", "Read notification info from a json file")
+	AudiencePlayersFlag = AudienceFlagSet.String("players", "", "Json export of players (array or one player per line). If empty, players are fetched with the auth key")
+	AudienceSegmentsFlag = AudienceFlagSet.String("segments", "", "Json file with the list of segments. If empty, segments are fetched with the auth key")
+	AudienceAuthFlag = AudienceFlagSet.String("auth", "", `Your "API Auth Key" on the GameThrive Application Settings page`)
+	AudienceListFlag = AudienceFlagSet.Bool("list", false, "Print the id of every matched player")
+}
+
+func NotificationAudience(args ...string) {
+	AudienceFlagSet.Parse(args)
+	if len(*AudienceNotificationFlag) <= 0 {
+		fmt.Println("Error: json flag is requried")
+		return
+	}
+	notification := new(gamethrive.Notification)
+	err := readJson(*AudienceNotificationFlag, notification)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	c := newClient()
+	var segments []gamethrive.Segment
+	if len(*AudienceSegmentsFlag) > 0 {
+		err = readJson(*AudienceSegmentsFlag, &segments)
+	} else if len(notification.IncludedSegments)+len(notification.ExcludedSegments) > 0 {
+		segments, err = c.Segments.List(notification.AppId, *AudienceAuthFlag)
+	}
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	var players []gamethrive.Player
+	if len(*AudiencePlayersFlag) > 0 {
+		var file *os.File
+		file, err = os.Open(*AudiencePlayersFlag)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+			return
+		}
+		players, err = gamethrive.ReadPlayers(file)
+		file.Close()
+	} else {
+		players, err = c.Players.All(notification.AppId, *AudienceAuthFlag)
+	}
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	audience, err := gamethrive.NewSegmentEngine(segments).Evaluate(notification, players)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	fmt.Printf("Notification would target %d of %d players\n", audience.Count, len(players))
+	names := make([]string, 0, len(audience.Segments))
+	for name := range audience.Segments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  %s: %d\n", name, audience.Segments[name])
+	}
+	missing, err := locale.Missing(notification, audience.Players)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	langs := make([]string, 0, len(missing))
+	for lang := range missing {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		fmt.Printf("Warning: %d players speak %s, which has no content\n", missing[lang], lang)
+	}
+	if *AudienceListFlag {
+		for _, p := range audience.Players {
+			fmt.Println(p.Id)
+		}
+	}
+}
+
+func HelpNotificationAudience(args ...string) {
+	fmt.Println("Counts the players a notification would be sent to, without sending it")
+	AudienceFlagSet.PrintDefaults()
+}
+
+func readJson(path string, v interface{}) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewDecoder(file).Decode(v)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"../gamethrive"
+)
+
+var (
+	AuditFlagSet      *flag.FlagSet
+	AuditLogFlag      *string
+	AuditKeyFlag      *string
+	AuditOperatorFlag *string
+	AuditMethodFlag   *string
+	AuditPathFlag     *string
+	AuditOutcomeFlag  *string
+	AuditSinceFlag    *string
+	AuditUntilFlag    *string
+)
+
+func init() {
+	AuditFlagSet = flag.NewFlagSet("audit", flag.ContinueOnError)
+	AuditLogFlag = AuditFlagSet.String("log", os.Getenv("GAMETHRIVE_AUDIT_LOG"), "Audit log file (defaults to $GAMETHRIVE_AUDIT_LOG)")
+	AuditKeyFlag = AuditFlagSet.String("key", os.Getenv("GAMETHRIVE_AUDIT_KEY"), "Key the audit log is signed with (defaults to $GAMETHRIVE_AUDIT_KEY)")
+	AuditOperatorFlag = AuditFlagSet.String("operator", "", "Only show calls made by this operator")
+	AuditMethodFlag = AuditFlagSet.String("method", "", "Only show calls with this http method")
+	AuditPathFlag = AuditFlagSet.String("path", "", `Only show calls whose path starts with this one (e.g. "/api/v1/notifications")`)
+	AuditOutcomeFlag = AuditFlagSet.String("outcome", "", `Only show calls with this outcome, "success" or "failure"`)
+	AuditSinceFlag = AuditFlagSet.String("since", "", `Only show calls after this date (RFC3339) or duration ago (e.g. "24h")`)
+	AuditUntilFlag = AuditFlagSet.String("until", "", `Only show calls before this date (RFC3339) or duration ago`)
+}
+
+// newClient creates the client used by every command. When
+// $GAMETHRIVE_AUDIT_LOG and $GAMETHRIVE_AUDIT_KEY are set, mutating calls
+// are recorded in the audit log on behalf of $GAMETHRIVE_OPERATOR, or
+// $USER. A log failing verification is reported but still used.
+func newClient() *gamethrive.Client {
+	c := gamethrive.NewClient(nil)
+	path, key := os.Getenv("GAMETHRIVE_AUDIT_LOG"), os.Getenv("GAMETHRIVE_AUDIT_KEY")
+	if len(path) <= 0 {
+		return c
+	}
+	log, err := gamethrive.OpenAuditLog(path, []byte(key))
+	if log == nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Warning: audit log %s failed verification: %s; new calls are chained to the last valid record\n", path, err.Error())
+	}
+	c.Audit = log
+	c.OnAuditError = func(err error) {
+		fmt.Printf("Warning: call not recorded in the audit log: %s\n", err.Error())
+	}
+	c.Operator = os.Getenv("GAMETHRIVE_OPERATOR")
+	if len(c.Operator) <= 0 {
+		c.Operator = os.Getenv("USER")
+	}
+	return c
+}
+
+func AuditQuery(args ...string) {
+	AuditFlagSet.Parse(args)
+	query := gamethrive.AuditQuery{
+		Operator: *AuditOperatorFlag,
+		Method:   *AuditMethodFlag,
+		Path:     *AuditPathFlag,
+		Outcome:  *AuditOutcomeFlag,
+	}
+	var err error
+	if query.Since, err = parseAuditTime(*AuditSinceFlag); err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	if query.Until, err = parseAuditTime(*AuditUntilFlag); err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	records, err := gamethrive.ReadAuditLog(*AuditLogFlag, []byte(*AuditKeyFlag))
+	enc := json.NewEncoder(os.Stdout)
+	for i := range records {
+		if query.Match(&records[i]) {
+			enc.Encode(&records[i])
+		}
+	}
+	if err != nil {
+		fmt.Printf("Error: %s (after %d records)\n", err.Error(), len(records))
+	}
+}
+
+func HelpAuditQuery(args ...string) {
+	fmt.Println("Verifies the audit log and prints the records matching the filters")
+	AuditFlagSet.PrintDefaults()
+}
+
+func parseAuditTime(str string) (time.Time, error) {
+	if len(str) <= 0 {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(str); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, str)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid time %q, expected RFC3339 or a duration", str)
+	}
+	return t, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"../gamethrive"
+)
+
+var (
+	CampaignFlagSet     *flag.FlagSet
+	CampaignFileFlag    *string
+	CampaignAuthFlag    *string
+	CampaignHorizonFlag *string
+)
+
+func init() {
+	CampaignFlagSet = flag.NewFlagSet("campaign", flag.ContinueOnError)
+	CampaignFileFlag = CampaignFlagSet.String("file", "campaigns.json", "Json file declaring the campaigns, their templates and schedules")
+	CampaignAuthFlag = CampaignFlagSet.String("auth", "", `Your "API Auth Key" on the GameThrive Application Settings page`)
+	CampaignHorizonFlag = CampaignFlagSet.String("horizon", "168h", "How far ahead recurring campaigns are scheduled")
+}
+
+func CampaignPlan(args ...string) {
+	CampaignFlagSet.Parse(args)
+	c := newClient()
+	plan, err := currentPlan(c)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	printPlan(plan)
+}
+
+func HelpCampaignPlan(args ...string) {
+	fmt.Println("Shows the notifications that campaign apply would create or cancel")
+	CampaignFlagSet.PrintDefaults()
+}
+
+func CampaignApply(args ...string) {
+	CampaignFlagSet.Parse(args)
+	c := newClient()
+	plan, err := currentPlan(c)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	printPlan(plan)
+	applied, err := plan.Apply(c, *CampaignAuthFlag)
+	if err != nil {
+		fmt.Printf("Error: %s (%d of %d changes applied)\n", err.Error(), applied, len(plan.Steps))
+		return
+	}
+	fmt.Printf("%d changes applied\n", applied)
+}
+
+func HelpCampaignApply(args ...string) {
+	fmt.Println("Creates and cancels notifications so that the scheduled ones match the campaign file")
+	CampaignFlagSet.PrintDefaults()
+}
+
+func currentPlan(c *gamethrive.Client) (*gamethrive.Plan, error) {
+	horizon, err := time.ParseDuration(*CampaignHorizonFlag)
+	if err != nil {
+		return nil, err
+	}
+	file, err := gamethrive.LoadCampaignFile(*CampaignFileFlag)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := c.Notifications.All(file.AppId, *CampaignAuthFlag)
+	if err != nil {
+		return nil, err
+	}
+	return file.Plan(existing, time.Now(), horizon)
+}
+
+func printPlan(plan *gamethrive.Plan) {
+	for _, step := range plan.Steps {
+		switch step.Action {
+		case gamethrive.PlanCreate:
+			fmt.Printf("+ create\t%s\t%s\n", step.Campaign, step.SendAt.Format(time.RFC3339))
+		case gamethrive.PlanCancel:
+			fmt.Printf("- cancel\t%s\t%s\t%s\n", step.Campaign, step.SendAt.Format(time.RFC3339), step.Id)
+		}
+	}
+	fmt.Printf("%d to create or cancel, %d unchanged\n", len(plan.Steps), plan.Unchanged)
+}
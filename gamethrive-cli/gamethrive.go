@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"../gamethrive"
+	"../gamethrive/locale"
 )
 
 var (
@@ -20,26 +23,40 @@ var (
 	PlayerDeviceTypeFlag    *string
 	PlayerIdentifierFlag    *string
 	PlayerLanguageFlag      *string
-	PlayerTimezoneFlag      *int
+	PlayerTimezoneFlag      *string
 	PlayerDeviceModelFlag   *string
 	PlayerDeviceOSFlag      *string
 	PlayerGameVerionFlag    *string
 	PlayerAdvertisingIdFlag *string
+	PlayerExternalIdFlag    *string
 	PlayerSessionCountFlag  *int
 	PlayerTagsFlag          *string
 	PlayerAmountSpentFlag   *float64
-	PlayerCreatedAtFlag     *int
-	PlayerLastActiveFlag    *int
-	PlayerPlaytimeFlag      *int
+	PlayerCreatedAtFlag     *string
+	PlayerLastActiveFlag    *string
+	PlayerPlaytimeFlag      *string
+	PlayerPreviousFlag      *string
+	PlayerIdentitiesFlag    *string
+	PlayerAuthFlag          *string
 
 	PlayerAmountFlagSet    *flag.FlagSet
 	PlayerAmountIdFlag     *string
 	PlayerAmountAmountFlag *float64
 
+	PlayerPurchaseFlagSet    *flag.FlagSet
+	PlayerPurchaseIdFlag     *string
+	PlayerPurchaseItemsFlag  *string
+	PlayerPurchaseLedgerFlag *string
+
+	PlayerTagsFlagSet    *flag.FlagSet
+	PlayerTagsIdFlag     *string
+	PlayerTagsSetFlag    *string
+	PlayerTagsDeleteFlag *string
+
 	PlayerPlaytimeFlagSet   *flag.FlagSet
 	PlayerPlaytimeIdFlag    *string
 	PlayerPlaytimeStateFlag *string
-	PlayerPlaytimeTimeFlag  *int
+	PlayerPlaytimeTimeFlag  *string
 
 	NotificationFlagSet                   *flag.FlagSet
 	NotificationJsonPathFlag              *string
@@ -48,11 +65,19 @@ var (
 	NotificationIsIOSFlag                 *bool
 	NotificationIsAndroidFlag             *bool
 	NotificationContentsFlag              *string
+	NotificationHeadingsFlag              *string
+	NotificationButtonsFlag               *string
+	NotificationCatalogFlag               *string
+	NotificationMessageFlag               *string
 	NotificationIncludedSegmentsFlag      *string
 	NotificationExcludedSegmentsFlag      *string
 	NotificationIncludedPlayerIdsFlag     *string
 	NotificationIncludedIOSTokensFlag     *string
 	NotificationIncludedAndroidRegIdsFlag *string
+	NotificationIncludedExternalIdsFlag   *string
+	NotificationIdentitiesFlag            *string
+	NotificationFiltersFlag               *string
+	NotificationVerifySegmentsFlag        *bool
 	NotificationIOSBadgeTypeFlag          *string
 	NotificationIOSBadgeCountFlag         *int
 	NotificationIOSSoundFlag              *string
@@ -61,11 +86,21 @@ var (
 	NotificationURLFlag                   *string
 	NotificationSendAfterFlag             *string
 	NotificationSendUserActiveTimeFlag    *bool
+	NotificationQuietHoursFlag            *string
+	NotificationMaxPerDayFlag             *int
+	NotificationDeferFlag                 *bool
+	NotificationHistoryFlag               *string
+	NotificationDedupWindowFlag           *string
+	NotificationDedupStoreFlag            *string
+	NotificationAppsFlag                  *string
 
 	NotificationOpenFlagSet    *flag.FlagSet
 	NotificationOpenIdFlag     *string
 	NotificationOpenAppIdFlag  *string
 	NotificationOpenOpenedFlag *bool
+	NotificationOpenPlayerFlag *string
+	NotificationOpenActionFlag *string
+	NotificationOpenURLFlag    *string
 )
 
 func init() {
@@ -73,29 +108,43 @@ func init() {
 	PlayerJsonPathFlag = PlayerFlagSet.String("json", "", "Read player info from a json file")
 	PlayerAppIdFlag = PlayerFlagSet.String("app_id", "", "Your GameThrive's application key")
 	PlayerIdFlag = PlayerFlagSet.String("id", "", "Gamethrive identifier of the player")
-	PlayerDeviceTypeFlag = PlayerFlagSet.String("device_type", "ios", `"ios", "android" or "amazon"`)
+	PlayerDeviceTypeFlag = PlayerFlagSet.String("device_type", "ios", `"ios", "android", "amazon", "windowsphone", "chromeapp", "chrome", "safari", "firefox", "macos" or "email"`)
 	PlayerIdentifierFlag = PlayerFlagSet.String("identifier", "", "Push notification identifier from Google or Apple")
-	PlayerLanguageFlag = PlayerFlagSet.String("language", "", "Language code. Typically lower case two letters, except for chinese")
-	PlayerTimezoneFlag = PlayerFlagSet.Int("timezone", 0, "Number of seconds away from GMT")
+	PlayerLanguageFlag = PlayerFlagSet.String("language", "", `Language code. Typically lower case two letters, except for chinese ("zh-Hans" or "zh-Hant"). Tags such as "en-US" are normalized`)
+	PlayerTimezoneFlag = PlayerFlagSet.String("timezone", "0", `Offset from GMT, in seconds or as a duration (e.g. "-5h" or "5h30m")`)
 	PlayerDeviceModelFlag = PlayerFlagSet.String("device_model", "", "Device model")
 	PlayerDeviceOSFlag = PlayerFlagSet.String("device_os", "", "Device operating system version")
 	PlayerGameVerionFlag = PlayerFlagSet.String("game_version", "", "Version of the game")
 	PlayerAdvertisingIdFlag = PlayerFlagSet.String("ad_id", "", "Advertising id for Android devices and identifierForVendor for iOS devices")
+	PlayerExternalIdFlag = PlayerFlagSet.String("external_user_id", "", "Identifier of the player in your own backend")
 	PlayerSessionCountFlag = PlayerFlagSet.Int("session_count", 1, "Number of times the player has played the game, defaults to 1")
 	PlayerTagsFlag = PlayerFlagSet.String("tags", "{}", "Custom tags for the player (a json string)")
 	PlayerAmountSpentFlag = PlayerFlagSet.Float64("amount_spent", 0.0, "Amount the player has spent in USD, up to two decimal places")
-	PlayerCreatedAtFlag = PlayerFlagSet.Int("created_at", 0, "Unixtime when the player joined the game")
-	PlayerLastActiveFlag = PlayerFlagSet.Int("last_active", 0, "Unixtime when the player was last active")
-	PlayerPlaytimeFlag = PlayerFlagSet.Int("playtime", 0, "Seconds player was running your app.")
+	PlayerCreatedAtFlag = PlayerFlagSet.String("created_at", "", "When the player joined the game (unixtime or RFC3339 date)")
+	PlayerLastActiveFlag = PlayerFlagSet.String("last_active", "", "When the player was last active (unixtime or RFC3339 date)")
+	PlayerPlaytimeFlag = PlayerFlagSet.String("playtime", "0", `Time player was running your app, in seconds or as a duration (e.g. "2h30m")`)
+	PlayerAuthFlag = PlayerFlagSet.String("auth", "", `Your "API Auth Key", used to look up existing players`)
+	PlayerPreviousFlag = PlayerFlagSet.String("previous", "", "Json file with the last known player, only changed fields are updated")
+	PlayerIdentitiesFlag = PlayerFlagSet.String("identities", "", "File where player ids are recorded by device and external user id")
 
 	PlayerAmountFlagSet = flag.NewFlagSet("player amount", flag.ContinueOnError)
 	PlayerAmountIdFlag = PlayerAmountFlagSet.String("id", "", "Gamethrive identifier of the player")
 	PlayerAmountAmountFlag = PlayerAmountFlagSet.Float64("amount", 0.0, "New amount in USD, up to two decimal places")
 
+	PlayerPurchaseFlagSet = flag.NewFlagSet("player purchase", flag.ContinueOnError)
+	PlayerPurchaseIdFlag = PlayerPurchaseFlagSet.String("id", "", "Gamethrive identifier of the player")
+	PlayerPurchaseItemsFlag = PlayerPurchaseFlagSet.String("items", "[]", `Purchased items as json, e.g. [{"sku":"gems","quantity":1,"price":4.99,"currency":"EUR"}]`)
+	PlayerPurchaseLedgerFlag = PlayerPurchaseFlagSet.String("ledger", "", "File where purchases are recorded (json lines)")
+
+	PlayerTagsFlagSet = flag.NewFlagSet("player tags", flag.ContinueOnError)
+	PlayerTagsIdFlag = PlayerTagsFlagSet.String("id", "", "Gamethrive identifier of the player")
+	PlayerTagsSetFlag = PlayerTagsFlagSet.String("set", "{}", "Tags to add or change (a json string)")
+	PlayerTagsDeleteFlag = PlayerTagsFlagSet.String("delete", "", "Names of tags to remove (separated by commas)")
+
 	PlayerPlaytimeFlagSet = flag.NewFlagSet("player playtime", flag.ContinueOnError)
 	PlayerPlaytimeIdFlag = PlayerPlaytimeFlagSet.String("id", "", "Gamethrive identifier of the player")
 	PlayerPlaytimeStateFlag = PlayerPlaytimeFlagSet.String("state", "", "Required to indicate we are incrementing")
-	PlayerPlaytimeTimeFlag = PlayerPlaytimeFlagSet.Int("active_time", 0, "Number of seconds player was running your app")
+	PlayerPlaytimeTimeFlag = PlayerPlaytimeFlagSet.String("active_time", "0", `Time player was running your app, in seconds or as a duration (e.g. "15m")`)
 
 	NotificationFlagSet = flag.NewFlagSet("notification", flag.ContinueOnError)
 	NotificationJsonPathFlag = NotificationFlagSet.String("json", "", "Read notification info from a json file")
@@ -104,24 +153,42 @@ func init() {
 	NotificationIsIOSFlag = NotificationFlagSet.Bool("ios", false, "Send notification to iOS players")
 	NotificationIsAndroidFlag = NotificationFlagSet.Bool("android", false, "Send notification to Android players")
 	NotificationContentsFlag = NotificationFlagSet.String("contents", `{"en":""}`, "Message contents to send to players, \"en\" (English) is required")
+	NotificationHeadingsFlag = NotificationFlagSet.String("headings", "", `Notification titles by language, shown on Android (as json), e.g. {"en":"New levels"}`)
+	NotificationButtonsFlag = NotificationFlagSet.String("buttons", "", `Action buttons (as json), e.g. [{"id":"play","text":"Play now"}]`)
+	NotificationCatalogFlag = NotificationFlagSet.String("catalog", "", "Translations catalog (.json or gettext .po files, separated by commas) to take contents from")
+	NotificationMessageFlag = NotificationFlagSet.String("message", "", "Identifier of the catalog message used as contents")
 	NotificationIncludedSegmentsFlag = NotificationFlagSet.String("included_segments", "", "Names of segments to send the message to (separated by commas)")
 	NotificationExcludedSegmentsFlag = NotificationFlagSet.String("excluded_segments", "", "Names of segments to exclude players from (separated by commas)")
 	NotificationIncludedPlayerIdsFlag = NotificationFlagSet.String("include_player_ids", "", "Specific players to send your notification to (separated by commas)")
 	NotificationIncludedIOSTokensFlag = NotificationFlagSet.String("include_ios_tokens", "", "Specific iOS device tokens to send the notification to (separated by commas)")
 	NotificationIncludedAndroidRegIdsFlag = NotificationFlagSet.String("include_android_reg_ids", "", "Specific Android registration ids to send the notification to (separated by commas)")
+	NotificationIncludedExternalIdsFlag = NotificationFlagSet.String("include_external_user_ids", "", "Players to send the notification to by your own user ids (separated by commas), resolved with the identities file")
+	NotificationIdentitiesFlag = NotificationFlagSet.String("identities", "", "File where player ids are recorded by external user id, as written by players new and upsert")
+	NotificationFiltersFlag = NotificationFlagSet.String("filters", "", `Filters players to send to (as json), e.g. [{"field":"tag","key":"level","relation":">","value":"10"}]`)
+	NotificationVerifySegmentsFlag = NotificationFlagSet.Bool("verify_segments", false, "Check that the included and excluded segments exist before sending")
 	NotificationIOSBadgeTypeFlag = NotificationFlagSet.String("ios_badgeType", "none", `Options are: "none", "setto", or "increase"`)
 	NotificationIOSBadgeCountFlag = NotificationFlagSet.Int("ios_badgeCount", 0, "Sets or increases the badge icon on the device")
 	NotificationIOSSoundFlag = NotificationFlagSet.String("ios_sound", "", "Sound file that is included in your app to play")
 	NotificationAndroidSoundFlag = NotificationFlagSet.String("android_sound", "", "Sound file that is included in your app to play")
 	NotificationDataFlag = NotificationFlagSet.String("data", "", "Custom key value pair hash that you can programmatically read in your app's code (as json string)")
 	NotificationURLFlag = NotificationFlagSet.String("url", "", "When the player opens the notification their web browser will open this url")
-	NotificationSendAfterFlag = NotificationFlagSet.String("send_after", "", `Schedule notification for future delivery (Format: "Mon Jan 02 2006 15:04:05 MST-0700")`)
+	NotificationSendAfterFlag = NotificationFlagSet.String("send_after", "", `Schedule notification for future delivery (e.g. "Mon Jan 02 2006 15:04:05 GMT-0700", "in 2h" or "tomorrow 18:00 Europe/Madrid")`)
 	NotificationSendUserActiveTimeFlag = NotificationFlagSet.Bool("send_at_user_active_time", false, "Sends your notification at the time of day the user last opened your app")
+	NotificationQuietHoursFlag = NotificationFlagSet.String("quiet_hours", "", `Local hours in which players listed in include_player_ids are not notified (e.g. "22:00-08:00")`)
+	NotificationMaxPerDayFlag = NotificationFlagSet.Int("max_per_day", 0, "Maximum notifications a player listed in include_player_ids gets in a day (0 means no limit)")
+	NotificationDeferFlag = NotificationFlagSet.Bool("defer", false, "Deliver notifications falling in quiet hours when they end, instead of dropping them")
+	NotificationHistoryFlag = NotificationFlagSet.String("history", "send_history.json", "File where sends are recorded for max_per_day")
+	NotificationDedupWindowFlag = NotificationFlagSet.String("dedup_window", "", `Do not send the notification again if it was already sent within this time (e.g. "10m")`)
+	NotificationAppsFlag = NotificationFlagSet.String("apps", "", `Send to several applications at once, as app_id:auth pairs (separated by commas). Only segments and filters can be targeted`)
+	NotificationDedupStoreFlag = NotificationFlagSet.String("dedup_store", "sent_notifications.json", "File where sent notifications are recorded for dedup_window")
 
 	NotificationOpenFlagSet = flag.NewFlagSet("notification open", flag.ContinueOnError)
 	NotificationOpenIdFlag = NotificationOpenFlagSet.String("id", "", "Identifier of the notification")
 	NotificationOpenAppIdFlag = NotificationOpenFlagSet.String("app_id", "", "Your GameThrive's application key")
 	NotificationOpenOpenedFlag = NotificationOpenFlagSet.Bool("opened", true, "Required to indicate the notification was openned")
+	NotificationOpenPlayerFlag = NotificationOpenFlagSet.String("player_id", "", "Identifier of the player that opened the notification")
+	NotificationOpenActionFlag = NotificationOpenFlagSet.String("action_id", "", "Identifier of the action button clicked, if any")
+	NotificationOpenURLFlag = NotificationOpenFlagSet.String("launch_url", "", "Url launched when opening the notification, if any")
 }
 
 func main() {
@@ -136,10 +203,22 @@ func main() {
 				"handler": Handler(PlayersUpdate),
 				"usage":   "Updates player attributes",
 			},
+			"upsert": map[string]interface{}{
+				"handler": Handler(PlayersUpsert),
+				"usage":   "Updates the player of a device or creates it",
+			},
 			"amount": map[string]interface{}{
 				"handler": Handler(PlayerUpdateAmount),
 				"usage":   "Updates player's amount",
 			},
+			"purchase": map[string]interface{}{
+				"handler": Handler(PlayerPurchase),
+				"usage":   "Tracks a purchase of items in any currency",
+			},
+			"tags": map[string]interface{}{
+				"handler": Handler(PlayerTags),
+				"usage":   "Changes or removes player tags",
+			},
 			"session": map[string]interface{}{
 				"handler": Handler(PlayerSession),
 				"usage":   "Updates and increments player session count",
@@ -158,6 +237,74 @@ func main() {
 				"handler": Handler(NotificationOpen),
 				"usage":   "Track that a push notification was opened",
 			},
+			"audience": map[string]interface{}{
+				"handler": Handler(NotificationAudience),
+				"usage":   "Counts the players a notification would target",
+			},
+			"lint": map[string]interface{}{
+				"handler": Handler(NotificationLint),
+				"usage":   "Checks the payload size and characters of a notification",
+			},
+			"preview": map[string]interface{}{
+				"handler": Handler(NotificationPreview),
+				"usage":   "Renders how a notification will look on devices",
+			},
+		},
+		"segments": map[string]interface{}{
+			"list": map[string]interface{}{
+				"handler": Handler(SegmentsList),
+				"usage":   "Lists the segments of an application",
+			},
+			"new": map[string]interface{}{
+				"handler": Handler(SegmentsNew),
+				"usage":   "Creates a segment",
+			},
+			"delete": map[string]interface{}{
+				"handler": Handler(SegmentsDelete),
+				"usage":   "Deletes a segment",
+			},
+		},
+		"scheduler": map[string]interface{}{
+			"run": map[string]interface{}{
+				"handler": Handler(SchedulerRun),
+				"usage":   "Runs recurring notification campaigns",
+			},
+		},
+		"audit": map[string]interface{}{
+			"query": map[string]interface{}{
+				"handler": Handler(AuditQuery),
+				"usage":   "Shows the audit log of mutating calls",
+			},
+		},
+		"campaign": map[string]interface{}{
+			"plan": map[string]interface{}{
+				"handler": Handler(CampaignPlan),
+				"usage":   "Shows the changes needed to match a campaign file",
+			},
+			"apply": map[string]interface{}{
+				"handler": Handler(CampaignApply),
+				"usage":   "Schedules and cancels notifications to match a campaign file",
+			},
+		},
+		"outbox": map[string]interface{}{
+			"dead": map[string]interface{}{
+				"handler": Handler(OutboxDead),
+				"usage":   "Lists undeliverable outbox entries",
+			},
+			"retry": map[string]interface{}{
+				"handler": Handler(OutboxRetry),
+				"usage":   "Requeues an undeliverable outbox entry",
+			},
+		},
+		"webhook": map[string]interface{}{
+			"listen": map[string]interface{}{
+				"handler": Handler(WebhookListen),
+				"usage":   "Receives webhook events and prints them",
+			},
+			"test": map[string]interface{}{
+				"handler": Handler(WebhookTest),
+				"usage":   "Posts sample events to a webhook receiver",
+			},
 		},
 		"help": map[string]interface{}{
 			"players": map[string]interface{}{
@@ -167,9 +314,18 @@ func main() {
 				"update": map[string]interface{}{
 					"handler": Handler(HelpPlayersUpdate),
 				},
+				"upsert": map[string]interface{}{
+					"handler": Handler(HelpPlayersUpsert),
+				},
 				"amount": map[string]interface{}{
 					"handler": Handler(HelpPlayersUpdateAmount),
 				},
+				"purchase": map[string]interface{}{
+					"handler": Handler(HelpPlayerPurchase),
+				},
+				"tags": map[string]interface{}{
+					"handler": Handler(HelpPlayerTags),
+				},
 				"session": map[string]interface{}{
 					"handler": Handler(HelpPlayerSession),
 				},
@@ -184,6 +340,60 @@ func main() {
 				"open": map[string]interface{}{
 					"handler": Handler(HelpNotificationOpen),
 				},
+				"audience": map[string]interface{}{
+					"handler": Handler(HelpNotificationAudience),
+				},
+				"lint": map[string]interface{}{
+					"handler": Handler(HelpNotificationLint),
+				},
+				"preview": map[string]interface{}{
+					"handler": Handler(HelpNotificationPreview),
+				},
+			},
+			"segments": map[string]interface{}{
+				"list": map[string]interface{}{
+					"handler": Handler(HelpSegmentsList),
+				},
+				"new": map[string]interface{}{
+					"handler": Handler(HelpSegmentsNew),
+				},
+				"delete": map[string]interface{}{
+					"handler": Handler(HelpSegmentsDelete),
+				},
+			},
+			"scheduler": map[string]interface{}{
+				"run": map[string]interface{}{
+					"handler": Handler(HelpSchedulerRun),
+				},
+			},
+			"audit": map[string]interface{}{
+				"query": map[string]interface{}{
+					"handler": Handler(HelpAuditQuery),
+				},
+			},
+			"campaign": map[string]interface{}{
+				"plan": map[string]interface{}{
+					"handler": Handler(HelpCampaignPlan),
+				},
+				"apply": map[string]interface{}{
+					"handler": Handler(HelpCampaignApply),
+				},
+			},
+			"outbox": map[string]interface{}{
+				"dead": map[string]interface{}{
+					"handler": Handler(HelpOutboxDead),
+				},
+				"retry": map[string]interface{}{
+					"handler": Handler(HelpOutboxRetry),
+				},
+			},
+			"webhook": map[string]interface{}{
+				"listen": map[string]interface{}{
+					"handler": Handler(HelpWebhookListen),
+				},
+				"test": map[string]interface{}{
+					"handler": Handler(HelpWebhookTest),
+				},
 			},
 			"usage": "Shows usage about each command. Example: help players new",
 		},
@@ -242,7 +452,11 @@ func PlayersNew(args ...string) {
 		fmt.Printf("Error: %s\n", err.Error())
 		return
 	}
-	c := gamethrive.NewClient(nil)
+	c := newClient()
+	if c.Identities, err = identityStore(*PlayerIdentitiesFlag); err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
 	err = c.Players.New(player)
 	if err != nil {
 		fmt.Printf("Error: %s\n", err.Error())
@@ -258,12 +472,29 @@ func HelpPlayersNew(args ...string) {
 
 func PlayersUpdate(args ...string) {
 	PlayerFlagSet.Parse(args)
+	c := newClient()
+	if len(*PlayerPreviousFlag) > 0 {
+		previous, err := readPlayer(*PlayerPreviousFlag)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+			return
+		}
+		player, err := patchedPlayer(previous)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+			return
+		}
+		err = c.Players.Patch(previous, player)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+		}
+		return
+	}
 	player, err := currentPlayer()
 	if err != nil {
 		fmt.Printf("Error: %s\n", err.Error())
 		return
 	}
-	c := gamethrive.NewClient(nil)
 	err = c.Players.Update(player)
 	if err != nil {
 		fmt.Printf("Error: %s\n", err.Error())
@@ -274,7 +505,33 @@ func PlayersUpdate(args ...string) {
 func HelpPlayersUpdate(args ...string) {
 	fmt.Println("Updates player attributes.")
 	fmt.Println("Note: Updating tags will append to the player's existing tags.")
-	fmt.Println("      To remove an existing tag, update the tag with a value of a blank string.")
+	fmt.Println(`      To remove an existing tag use "players tags -delete".`)
+	PlayerFlagSet.PrintDefaults()
+}
+
+func PlayersUpsert(args ...string) {
+	PlayerFlagSet.Parse(args)
+	player, err := currentPlayer()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	c := newClient()
+	if c.Identities, err = identityStore(*PlayerIdentitiesFlag); err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	result, err := c.Players.Upsert(player, *PlayerAuthFlag)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	fmt.Printf("Player %s correctly. Player id is: \"%s\"\n", result, player.Id)
+}
+
+func HelpPlayersUpsert(args ...string) {
+	fmt.Println("Updates the player registered with the same identifier or ad_id, or creates it.")
+	fmt.Println("Existing players are looked up only if the auth flag is given.")
 	PlayerFlagSet.PrintDefaults()
 }
 
@@ -284,7 +541,7 @@ func PlayerUpdateAmount(args ...string) {
 		fmt.Println("Error: id flag is requried")
 		return
 	}
-	c := gamethrive.NewClient(nil)
+	c := newClient()
 	err := c.Players.UpdateAmount(*PlayerAmountIdFlag, *PlayerAmountAmountFlag)
 	if err != nil {
 		fmt.Printf("Error: %s\n", err.Error())
@@ -297,6 +554,65 @@ func HelpPlayersUpdateAmount(args ...string) {
 	PlayerAmountFlagSet.PrintDefaults()
 }
 
+func PlayerPurchase(args ...string) {
+	PlayerPurchaseFlagSet.Parse(args)
+	if len(*PlayerPurchaseIdFlag) <= 0 {
+		fmt.Println("Error: id flag is requried")
+		return
+	}
+	purchase := gamethrive.Purchase{PlayerId: *PlayerPurchaseIdFlag}
+	err := json.Unmarshal([]byte(*PlayerPurchaseItemsFlag), &purchase.Items)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	var ledger gamethrive.PurchaseLedger
+	if len(*PlayerPurchaseLedgerFlag) > 0 {
+		ledger = gamethrive.NewFileLedger(*PlayerPurchaseLedgerFlag)
+	}
+	c := newClient()
+	err = gamethrive.NewPurchaseTracker(c, nil, ledger).Track(&purchase)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	fmt.Printf("Purchase tracked: %.2f USD\n", purchase.USD)
+}
+
+func HelpPlayerPurchase(args ...string) {
+	fmt.Println("Tracks a purchase, converting its items to USD with an offline rates table.")
+	fmt.Println("Use negative quantities for refunds.")
+	PlayerPurchaseFlagSet.PrintDefaults()
+}
+
+func PlayerTags(args ...string) {
+	PlayerTagsFlagSet.Parse(args)
+	if len(*PlayerTagsIdFlag) <= 0 {
+		fmt.Println("Error: id flag is requried")
+		return
+	}
+	changes := gamethrive.NewTagChanges()
+	err := json.Unmarshal([]byte(*PlayerTagsSetFlag), &changes.Set)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	if len(*PlayerTagsDeleteFlag) > 0 {
+		changes.DeleteTags(strings.Split(*PlayerTagsDeleteFlag, ",")...)
+	}
+	c := newClient()
+	err = c.Players.UpdateTags(*PlayerTagsIdFlag, changes)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+}
+
+func HelpPlayerTags(args ...string) {
+	fmt.Println("Changes or removes player tags, leaving the other tags untouched.")
+	PlayerTagsFlagSet.PrintDefaults()
+}
+
 func PlayerSession(args ...string) {
 	PlayerFlagSet.Parse(args)
 	player, err := currentPlayer()
@@ -304,7 +620,7 @@ func PlayerSession(args ...string) {
 		fmt.Printf("Error: %s\n", err.Error())
 		return
 	}
-	c := gamethrive.NewClient(nil)
+	c := newClient()
 	err = c.Players.Session(player)
 	if err != nil {
 		fmt.Printf("Error: %s\n", err.Error())
@@ -323,9 +639,14 @@ func PlayerPlaytime(args ...string) {
 		fmt.Println("Error: id flag is requried")
 		return
 	}
-	c := gamethrive.NewClient(nil)
+	c := newClient()
 	state := stringToPlayState(*PlayerPlaytimeStateFlag)
-	err := c.Players.Playtime(*PlayerPlaytimeIdFlag, state, *PlayerPlaytimeTimeFlag)
+	activeTime, err := parseSeconds(*PlayerPlaytimeTimeFlag)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	err = c.Players.Playtime(*PlayerPlaytimeIdFlag, state, activeTime)
 	if err != nil {
 		fmt.Printf("Error: %s\n", err.Error())
 		return
@@ -344,8 +665,44 @@ func NotificationNew(args ...string) {
 		fmt.Printf("Error: %s\n", err.Error())
 		return
 	}
-	c := gamethrive.NewClient(nil)
+	c := newClient()
+	c.VerifySegments = *NotificationVerifySegmentsFlag
+	if len(notification.IncludedExternalUserIds) > 0 {
+		if len(*NotificationIdentitiesFlag) <= 0 {
+			fmt.Println("Error: identities flag is required to send to external user ids")
+			return
+		}
+		if c.Identities, err = identityStore(*NotificationIdentitiesFlag); err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+			return
+		}
+	}
+	if len(*NotificationDedupWindowFlag) > 0 {
+		window, err := time.ParseDuration(*NotificationDedupWindowFlag)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+			return
+		}
+		store, err := gamethrive.NewFileDedupStore(*NotificationDedupStoreFlag)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+			return
+		}
+		c.Dedup = gamethrive.NewDedupGuard(store, window)
+	}
+	if len(*NotificationAppsFlag) > 0 {
+		notificationFanOut(c, notification)
+		return
+	}
+	if len(*NotificationQuietHoursFlag) > 0 || *NotificationMaxPerDayFlag > 0 {
+		notificationNewWithPolicy(c, notification)
+		return
+	}
 	d, err := c.Notifications.New(notification, *NotificationAuthPathFlag)
+	if err == gamethrive.ErrDuplicateNotification {
+		fmt.Printf("Notification (%s) was already sent. Target: %d players\n", notification.Id, d)
+		return
+	}
 	if err != nil {
 		fmt.Printf("Error: %s\n", err.Error())
 		return
@@ -353,6 +710,83 @@ func NotificationNew(args ...string) {
 	fmt.Printf("Notification (%s) created sucessfully. Target: %d players\n", notification.Id, d)
 }
 
+func notificationFanOut(c *gamethrive.Client, notification *gamethrive.Notification) {
+	apps := map[string]string{}
+	for _, pair := range strings.Split(*NotificationAppsFlag, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 || len(parts[0]) <= 0 {
+			fmt.Printf("Error: invalid app %q, expected app_id:auth\n", pair)
+			return
+		}
+		apps[parts[0]] = parts[1]
+	}
+	results := c.Notifications.FanOut(notification, apps)
+	appIds := make([]string, 0, len(results))
+	for appId := range results {
+		appIds = append(appIds, appId)
+	}
+	sort.Strings(appIds)
+	for _, appId := range appIds {
+		result := results[appId]
+		if result.Err != nil {
+			fmt.Printf("%s\tError: %s\n", appId, result.Err.Error())
+			continue
+		}
+		if result.Duplicate {
+			fmt.Printf("%s\t%s\t%d players (already sent)\n", appId, result.Id, result.Recipients)
+			continue
+		}
+		fmt.Printf("%s\t%s\t%d players\n", appId, result.Id, result.Recipients)
+	}
+	fmt.Printf("Target: %d players\n", results.Recipients())
+}
+
+func notificationNewWithPolicy(c *gamethrive.Client, notification *gamethrive.Notification) {
+	history, err := gamethrive.NewFileSendHistory(*NotificationHistoryFlag)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	policy := gamethrive.NewSendPolicy(c, history)
+	policy.MaxPerDay = *NotificationMaxPerDayFlag
+	policy.Defer = *NotificationDeferFlag
+	if len(*NotificationQuietHoursFlag) > 0 {
+		policy.QuietStart, policy.QuietEnd, err = parseQuietHours(*NotificationQuietHoursFlag)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+			return
+		}
+	}
+	result, err := policy.Send(notification, *NotificationAuthPathFlag)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	fmt.Printf("Notifications %v created sucessfully. Target: %d players\n", result.NotificationIds, result.Recipients)
+	for _, s := range result.Deferred {
+		fmt.Printf("  deferred %s until %s\n", s.PlayerId, s.Until.Format(time.RFC3339))
+	}
+	for _, s := range result.Suppressed {
+		fmt.Printf("  suppressed %s (%s)\n", s.PlayerId, s.Reason)
+	}
+}
+
+func parseQuietHours(str string) (time.Duration, time.Duration, error) {
+	parts := strings.Split(str, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("Invalid quiet hours %q", str)
+	}
+	var bounds [2]time.Duration
+	for i, part := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return 0, 0, fmt.Errorf("Invalid quiet hours %q", str)
+		}
+		bounds[i] = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	return bounds[0], bounds[1], nil
+}
+
 func HelpNotificationNew(args ...string) {
 	fmt.Println("Create and deliver a new a Notification")
 	NotificationFlagSet.PrintDefaults()
@@ -368,12 +802,15 @@ func NotificationOpen(args ...string) {
 		fmt.Println("Error: app_id flag is requried")
 		return
 	}
-	c := gamethrive.NewClient(nil)
-	notification := gamethrive.Notification{
-		Id:    *NotificationOpenIdFlag,
-		AppId: *NotificationOpenAppIdFlag,
-	}
-	err := c.Notifications.Open(&notification, *NotificationOpenOpenedFlag)
+	c := newClient()
+	err := c.Notifications.TrackOpen(&gamethrive.OpenEvent{
+		NotificationId: *NotificationOpenIdFlag,
+		AppId:          *NotificationOpenAppIdFlag,
+		Opened:         *NotificationOpenOpenedFlag,
+		PlayerId:       *NotificationOpenPlayerFlag,
+		ActionId:       *NotificationOpenActionFlag,
+		LaunchURL:      *NotificationOpenURLFlag,
+	})
 	if err != nil {
 		fmt.Printf("Error: %s\n", err.Error())
 	}
@@ -385,26 +822,38 @@ func HelpNotificationOpen(args ...string) {
 }
 
 func currentPlayer() (*gamethrive.Player, error) {
-	player := new(gamethrive.Player)
 	if len(*PlayerJsonPathFlag) > 0 {
-		file, err := os.Open(*PlayerJsonPathFlag)
-		defer file.Close()
-		if err != nil {
-			return nil, err
-		}
-		err = json.NewDecoder(file).Decode(player)
-		return player, err
+		return readPlayer(*PlayerJsonPathFlag)
 	}
+	player := new(gamethrive.Player)
 	player.AppId = *PlayerAppIdFlag
 	player.Id = *PlayerIdFlag
-	player.DeviceType = stringToDeviceType(*PlayerDeviceTypeFlag)
+	deviceType, err := gamethrive.ParseDeviceType(*PlayerDeviceTypeFlag)
+	if err != nil {
+		return nil, err
+	}
+	player.DeviceType = deviceType
 	player.Identifier = *PlayerIdentifierFlag
-	player.Language = *PlayerLanguageFlag
-	player.Timezone = *PlayerTimezoneFlag
+	// The default device type says nothing about the identifier given.
+	if len(player.Identifier) > 0 && flagGiven(PlayerFlagSet, "device_type") {
+		err = deviceType.ValidateIdentifier(player.Identifier)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(*PlayerLanguageFlag) > 0 {
+		player.Language = normalizeLanguage(*PlayerLanguageFlag)
+	}
+	timezone, err := parseSeconds(*PlayerTimezoneFlag)
+	if err != nil {
+		return nil, err
+	}
+	player.Timezone = timezone
 	player.DeviceModel = *PlayerDeviceModelFlag
 	player.DeviceOS = *PlayerDeviceOSFlag
 	player.GameVersion = *PlayerGameVerionFlag
 	player.AdvertisingId = *PlayerAdvertisingIdFlag
+	player.ExternalUserId = *PlayerExternalIdFlag
 	player.SessionCount = *PlayerSessionCountFlag
 	tags, err := currentPlayerTags()
 	if err != nil {
@@ -412,12 +861,113 @@ func currentPlayer() (*gamethrive.Player, error) {
 	}
 	player.Tags = tags
 	player.AmountSpent = *PlayerAmountSpentFlag
-	player.CreatedAt = *PlayerCreatedAtFlag
-	player.LastActive = *PlayerLastActiveFlag
-	player.Playtime = *PlayerPlaytimeFlag
+	player.CreatedAt, err = parseUnixtime(*PlayerCreatedAtFlag)
+	if err != nil {
+		return nil, err
+	}
+	player.LastActive, err = parseUnixtime(*PlayerLastActiveFlag)
+	if err != nil {
+		return nil, err
+	}
+	player.Playtime, err = parseSeconds(*PlayerPlaytimeFlag)
+	if err != nil {
+		return nil, err
+	}
 	return player, nil
 }
 
+// flagGiven reports whether the flag name was set on the command line.
+func flagGiven(fs *flag.FlagSet, name string) bool {
+	given := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			given = true
+		}
+	})
+	return given
+}
+
+// patchedPlayer returns previous with the player flags given on the command
+// line, so flags left to their defaults are not taken as changes. Given
+// tags are added to the previous ones. With a json player, the file is the
+// updated player.
+func patchedPlayer(previous *gamethrive.Player) (*gamethrive.Player, error) {
+	flagged, err := currentPlayer()
+	if err != nil || len(*PlayerJsonPathFlag) > 0 {
+		return flagged, err
+	}
+	player := *previous
+	player.Tags = map[string]string{}
+	for k, v := range previous.Tags {
+		player.Tags[k] = v
+	}
+	PlayerFlagSet.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "app_id":
+			player.AppId = flagged.AppId
+		case "id":
+			player.Id = flagged.Id
+		case "device_type":
+			player.DeviceType = flagged.DeviceType
+		case "identifier":
+			player.Identifier = flagged.Identifier
+		case "language":
+			player.Language = flagged.Language
+		case "timezone":
+			player.Timezone = flagged.Timezone
+		case "device_model":
+			player.DeviceModel = flagged.DeviceModel
+		case "device_os":
+			player.DeviceOS = flagged.DeviceOS
+		case "game_version":
+			player.GameVersion = flagged.GameVersion
+		case "ad_id":
+			player.AdvertisingId = flagged.AdvertisingId
+		case "external_user_id":
+			player.ExternalUserId = flagged.ExternalUserId
+		case "session_count":
+			player.SessionCount = flagged.SessionCount
+		case "tags":
+			for k, v := range flagged.Tags {
+				player.Tags[k] = v
+			}
+		case "amount_spent":
+			player.AmountSpent = flagged.AmountSpent
+		case "created_at":
+			player.CreatedAt = flagged.CreatedAt
+		case "last_active":
+			player.LastActive = flagged.LastActive
+		case "playtime":
+			player.Playtime = flagged.Playtime
+		}
+	})
+	return &player, nil
+}
+
+// identityStore opens the identity store at path, or returns nil if path
+// is empty.
+func identityStore(path string) (gamethrive.IdentityStore, error) {
+	if len(path) <= 0 {
+		return nil, nil
+	}
+	store, err := gamethrive.NewFileIdentityStore(path)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func readPlayer(path string) (*gamethrive.Player, error) {
+	player := new(gamethrive.Player)
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	err = json.NewDecoder(file).Decode(player)
+	return player, err
+}
+
 func currentPlayerTags() (m map[string]string, err error) {
 	buffer := ioutil.NopCloser(strings.NewReader(*PlayerTagsFlag))
 	err = json.NewDecoder(buffer).Decode(&m)
@@ -438,7 +988,23 @@ func currentNotification() (*gamethrive.Notification, error) {
 	notification.AppId = *NotificationAppIdFlag
 	notification.IsIOS = *NotificationIsIOSFlag
 	notification.IsAndroid = *NotificationIsAndroidFlag
-	notification.Contents = currentNotificationContents()
+	contents, err := currentNotificationContents()
+	if err != nil {
+		return nil, err
+	}
+	notification.Contents = contents
+	if len(*NotificationHeadingsFlag) > 0 {
+		err := json.Unmarshal([]byte(*NotificationHeadingsFlag), &notification.Headings)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(*NotificationButtonsFlag) > 0 {
+		err := json.Unmarshal([]byte(*NotificationButtonsFlag), &notification.Buttons)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if len(*NotificationIncludedSegmentsFlag) > 0 {
 		notification.IncludedSegments = strings.Split(*NotificationIncludedSegmentsFlag, ",")
 	}
@@ -454,6 +1020,15 @@ func currentNotification() (*gamethrive.Notification, error) {
 	if len(*NotificationIncludedAndroidRegIdsFlag) > 0 {
 		notification.IncludedAndroidRegIds = strings.Split(*NotificationIncludedAndroidRegIdsFlag, ",")
 	}
+	if len(*NotificationIncludedExternalIdsFlag) > 0 {
+		notification.IncludedExternalUserIds = strings.Split(*NotificationIncludedExternalIdsFlag, ",")
+	}
+	if len(*NotificationFiltersFlag) > 0 {
+		err := json.Unmarshal([]byte(*NotificationFiltersFlag), &notification.Filters)
+		if err != nil {
+			return nil, err
+		}
+	}
 	notification.IOSBadgeType = stringToBadgeType(*NotificationIOSBadgeTypeFlag)
 	notification.IOSBadgeCount = *NotificationIOSBadgeCountFlag
 	notification.IOSSound = *NotificationIOSSoundFlag
@@ -461,20 +1036,50 @@ func currentNotification() (*gamethrive.Notification, error) {
 	notification.Data = currentNotificationData()
 	notification.URL = *NotificationURLFlag
 	if len(*NotificationSendAfterFlag) > 0 {
-		t, err := time.Parse("Mon Jan 02 2006 15:04:05 MST-0700", *NotificationSendAfterFlag)
+		t, err := gamethrive.ParseSendTime(*NotificationSendAfterFlag)
 		if err != nil {
 			return nil, err
 		}
-		notification.SendAfter = &t
+		notification.SendAfter = t
 	}
 	notification.SendUserActiveTime = *NotificationSendUserActiveTimeFlag
 	return notification, nil
 }
 
-func currentNotificationContents() (c map[string]string) {
+func currentNotificationContents() (map[string]string, error) {
+	var c map[string]string
 	buffer := ioutil.NopCloser(strings.NewReader(*NotificationContentsFlag))
 	json.NewDecoder(buffer).Decode(&c)
-	return
+	if len(*NotificationCatalogFlag) > 0 {
+		catalog := locale.Catalog{}
+		for _, path := range strings.Split(*NotificationCatalogFlag, ",") {
+			if err := catalog.LoadFile(path); err != nil {
+				return nil, err
+			}
+		}
+		if c == nil {
+			c = map[string]string{}
+		}
+		if len(c["en"]) <= 0 {
+			delete(c, "en")
+		}
+		// The catalog languages are normalized when loaded.
+		for lang, text := range catalog.Contents(*NotificationMessageFlag) {
+			c[lang] = text
+		}
+	}
+	return c, nil
+}
+
+// normalizeLanguage returns the supported language code of tag. Unknown
+// tags are passed through with a warning, as the API may know them.
+func normalizeLanguage(tag string) string {
+	lang, err := locale.Normalize(tag)
+	if err != nil {
+		fmt.Printf("Warning: %s, using it as given\n", err.Error())
+		return tag
+	}
+	return lang
 }
 
 func currentNotificationData() (c map[string]string) {
@@ -483,15 +1088,31 @@ func currentNotificationData() (c map[string]string) {
 	return
 }
 
-func stringToDeviceType(str string) gamethrive.DeviceType {
-	switch str {
-	case "android":
-		return gamethrive.Android
-	case "amazon":
-		return gamethrive.Amazon
-	default:
-		return gamethrive.IOS
+// parseSeconds accepts a number of seconds or a duration such as "2h30m".
+func parseSeconds(str string) (int, error) {
+	if secs, err := strconv.Atoi(str); err == nil {
+		return secs, nil
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid duration %q", str)
+	}
+	return int(d / time.Second), nil
+}
+
+// parseUnixtime accepts unixtime or a RFC3339 date.
+func parseUnixtime(str string) (int, error) {
+	if len(str) <= 0 {
+		return 0, nil
+	}
+	if secs, err := strconv.Atoi(str); err == nil {
+		return secs, nil
+	}
+	t, err := time.Parse(time.RFC3339, str)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid date %q, expected unixtime or RFC3339", str)
 	}
+	return int(t.Unix()), nil
 }
 
 func stringToPlayState(str string) gamethrive.PlaytimeState {
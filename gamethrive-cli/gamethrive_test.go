@@ -0,0 +1,84 @@
+package main
+
+import "testing"
+
+func TestParseSeconds(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		err  bool
+	}{
+		{"0", 0, false},
+		{"3600", 3600, false},
+		{"-18000", -18000, false},
+		{"-5h", -18000, false},
+		{"5h30m", 19800, false},
+		{"1m30.5s", 90, false},
+		{"", 0, true},
+		{"five", 0, true},
+	}
+	for _, test := range tests {
+		got, err := parseSeconds(test.in)
+		if got != test.want || (err != nil) != test.err {
+			t.Errorf("parseSeconds(%q) = %d, %v, want %d", test.in, got, err, test.want)
+		}
+	}
+}
+
+func TestParseUnixtime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		err  bool
+	}{
+		{"", 0, false},
+		{"1443096000", 1443096000, false},
+		{"2015-09-24T12:00:00Z", 1443096000, false},
+		{"2015-09-24T14:00:00+02:00", 1443096000, false},
+		{"2015-09-24", 0, true},
+		{"yesterday", 0, true},
+	}
+	for _, test := range tests {
+		got, err := parseUnixtime(test.in)
+		if got != test.want || (err != nil) != test.err {
+			t.Errorf("parseUnixtime(%q) = %d, %v, want %d", test.in, got, err, test.want)
+		}
+	}
+}
+
+func TestCurrentPlayer_identifier(t *testing.T) {
+	PlayerFlagSet.Parse([]string{"-id", "p1", "-identifier", "APA91b-reg"})
+	if _, err := currentPlayer(); err != nil {
+		t.Errorf("currentPlayer() without device_type = %v, want no identifier check", err)
+	}
+	PlayerFlagSet.Parse([]string{"-device_type", "ios", "-identifier", "APA91b-reg"})
+	if _, err := currentPlayer(); err == nil {
+		t.Error("currentPlayer() with an Android id for iOS expected error")
+	}
+}
+
+func TestCurrentPlayer_language(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"en-US", "en"},
+		{"zh_TW", "zh-Hant"},
+		{"tlh", "tlh"},
+	}
+	for _, test := range tests {
+		PlayerFlagSet.Parse([]string{"-id", "p1", "-identifier", "", "-language", test.in})
+		player, err := currentPlayer()
+		if err != nil || player.Language != test.want {
+			t.Errorf("currentPlayer() language %q = %v, %v, want %q", test.in, player, err, test.want)
+		}
+	}
+}
+
+func TestCurrentNotificationContents(t *testing.T) {
+	NotificationFlagSet.Parse([]string{"-contents", `{"en":"Hi","tlh":"nuqneH"}`})
+	contents, err := currentNotificationContents()
+	if err != nil || contents["en"] != "Hi" || contents["tlh"] != "nuqneH" {
+		t.Errorf("currentNotificationContents() = %v, %v, want contents as given", contents, err)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	"../gamethrive"
+)
+
+func NotificationLint(args ...string) {
+	NotificationFlagSet.Parse(args)
+	notification, err := currentNotification()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	report := gamethrive.LintNotification(notification)
+	for _, size := range report.Sizes {
+		fmt.Printf("%s\t%s\t%d/%d bytes\n", size.Platform, size.Language, size.Size, size.Limit)
+	}
+	for _, issue := range report.Issues {
+		fmt.Println(issue)
+	}
+	if !report.HasErrors() {
+		fmt.Println("Notification is ready to be sent")
+	}
+}
+
+func HelpNotificationLint(args ...string) {
+	fmt.Println("Checks offline the payload size and characters of a notification. It accepts the same flags as notifications new")
+	NotificationFlagSet.PrintDefaults()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"../gamethrive"
+)
+
+var (
+	OutboxFlagSet *flag.FlagSet
+	OutboxDirFlag *string
+	OutboxIdFlag  *string
+)
+
+func init() {
+	OutboxFlagSet = flag.NewFlagSet("outbox", flag.ContinueOnError)
+	OutboxDirFlag = OutboxFlagSet.String("dir", "outbox", "Directory where the outbox is stored")
+	OutboxIdFlag = OutboxFlagSet.String("id", "", "Identifier of the outbox entry")
+}
+
+func OutboxDead(args ...string) {
+	OutboxFlagSet.Parse(args)
+	o, err := gamethrive.NewOutbox(newClient(), *OutboxDirFlag)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	entries, err := o.DeadLetters()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	for _, e := range entries {
+		fmt.Printf("%s\t%s\t%s\t%d attempts\t%s\n", e.Id, e.Kind, e.CreatedAt.Format(time.RFC3339), e.Attempts, e.LastError)
+	}
+}
+
+func HelpOutboxDead(args ...string) {
+	fmt.Println("Lists the outbox entries that could not be delivered")
+	OutboxFlagSet.PrintDefaults()
+}
+
+func OutboxRetry(args ...string) {
+	OutboxFlagSet.Parse(args)
+	if len(*OutboxIdFlag) <= 0 {
+		fmt.Println("Error: id flag is requried")
+		return
+	}
+	o, err := gamethrive.NewOutbox(newClient(), *OutboxDirFlag)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	err = o.Requeue(*OutboxIdFlag)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+	}
+}
+
+func HelpOutboxRetry(args ...string) {
+	fmt.Println("Moves a dead outbox entry back to the pending queue")
+	OutboxFlagSet.PrintDefaults()
+}
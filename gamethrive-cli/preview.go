@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"../gamethrive"
+)
+
+var (
+	PreviewFlagSet     *flag.FlagSet
+	PreviewAppNameFlag *string
+	PreviewOutFlag     *string
+	PreviewTextFlag    *bool
+)
+
+func init() {
+	PreviewFlagSet = flag.NewFlagSet("notification preview", flag.ContinueOnError)
+	PreviewAppNameFlag = PreviewFlagSet.String("app_name", "Your game", "Application name shown in the banners")
+	PreviewOutFlag = PreviewFlagSet.String("out", "preview.html", "Html file where the preview is written")
+	PreviewTextFlag = PreviewFlagSet.Bool("text", false, "Print a text preview instead of writing the html file")
+}
+
+// addNotificationFlags lets the preview accept the same flags as
+// notifications new, which currentNotification reads.
+func addNotificationFlags() {
+	NotificationFlagSet.VisitAll(func(f *flag.Flag) {
+		if PreviewFlagSet.Lookup(f.Name) == nil {
+			PreviewFlagSet.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+}
+
+func NotificationPreview(args ...string) {
+	addNotificationFlags()
+	PreviewFlagSet.Parse(args)
+	notification, err := currentNotification()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	banners := gamethrive.NotificationBanners(notification, *PreviewAppNameFlag)
+	if *PreviewTextFlag {
+		gamethrive.RenderBannersText(os.Stdout, banners)
+		return
+	}
+	file, err := os.Create(*PreviewOutFlag)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	err = gamethrive.RenderBannersHTML(file, banners)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	fmt.Printf("Preview of %d banners written to %s\n", len(banners), *PreviewOutFlag)
+}
+
+func HelpNotificationPreview(args ...string) {
+	fmt.Println("Renders how a notification will look on iOS and Android devices. It accepts the same flags as notifications new")
+	addNotificationFlags()
+	PreviewFlagSet.PrintDefaults()
+}
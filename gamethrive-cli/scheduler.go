@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"../gamethrive"
+)
+
+var (
+	SchedulerFlagSet      *flag.FlagSet
+	SchedulerConfigFlag   *string
+	SchedulerStateFlag    *string
+	SchedulerIntervalFlag *time.Duration
+)
+
+func init() {
+	SchedulerFlagSet = flag.NewFlagSet("scheduler run", flag.ContinueOnError)
+	SchedulerConfigFlag = SchedulerFlagSet.String("config", "campaigns.json", "Json file with the list of campaigns to run")
+	SchedulerStateFlag = SchedulerFlagSet.String("state", "scheduler-state.json", "File where the next run of each campaign is stored")
+	SchedulerIntervalFlag = SchedulerFlagSet.Duration("interval", time.Minute, "How often to check for due campaigns")
+}
+
+func SchedulerRun(args ...string) {
+	SchedulerFlagSet.Parse(args)
+	file, err := os.Open(*SchedulerConfigFlag)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	var campaigns []*gamethrive.Campaign
+	err = json.NewDecoder(file).Decode(&campaigns)
+	file.Close()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	c := newClient()
+	s, err := gamethrive.NewScheduler(c, *SchedulerStateFlag)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	s.Interval = *SchedulerIntervalFlag
+	s.OnRun = func(campaign *gamethrive.Campaign, scheduled time.Time, notification *gamethrive.Notification, recipients int, err error) {
+		if err != nil {
+			fmt.Printf("%s: campaign %q failed: %s\n", scheduled.Format(time.RFC3339), campaign.Name, err.Error())
+			return
+		}
+		fmt.Printf("%s: campaign %q sent (%s) to %d players\n", scheduled.Format(time.RFC3339), campaign.Name, notification.Id, recipients)
+	}
+	for _, campaign := range campaigns {
+		err = s.Add(campaign)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+			return
+		}
+		st, _ := s.State(campaign.Name)
+		fmt.Printf("Campaign %q next run at %s\n", campaign.Name, st.NextRun.Format(time.RFC3339))
+	}
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		close(stop)
+	}()
+	err = s.Run(stop)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+	}
+}
+
+func HelpSchedulerRun(args ...string) {
+	fmt.Println("Runs recurring notification campaigns until interrupted.")
+	fmt.Println("The config file is a json list of campaigns, each one with a")
+	fmt.Println(`"name", a cron "spec" (e.g. "0 18 * * fri"), an optional "location",`)
+	fmt.Println(`a "catch_up" policy ("skip", "once" or "all"), the "auth" key and`)
+	fmt.Println(`the "notification" to send.`)
+	SchedulerFlagSet.PrintDefaults()
+}
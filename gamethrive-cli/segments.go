@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"../gamethrive"
+)
+
+var (
+	SegmentFlagSet     *flag.FlagSet
+	SegmentAuthFlag    *string
+	SegmentAppIdFlag   *string
+	SegmentIdFlag      *string
+	SegmentNameFlag    *string
+	SegmentFiltersFlag *string
+)
+
+func init() {
+	SegmentFlagSet = flag.NewFlagSet("segment", flag.ContinueOnError)
+	SegmentAuthFlag = SegmentFlagSet.String("auth", "", `Your "API Auth Key" on the GameThrive Application Settings page`)
+	SegmentAppIdFlag = SegmentFlagSet.String("app_id", "", "Your GameThrive's application key")
+	SegmentIdFlag = SegmentFlagSet.String("id", "", "Identifier of the segment")
+	SegmentNameFlag = SegmentFlagSet.String("name", "", "Name of the segment")
+	SegmentFiltersFlag = SegmentFlagSet.String("filters", "[]", `Filters of the segment as json, e.g. [{"field":"tag","key":"level","relation":">","value":"10"}]`)
+}
+
+func SegmentsList(args ...string) {
+	SegmentFlagSet.Parse(args)
+	c := newClient()
+	segments, err := c.Segments.List(*SegmentAppIdFlag, *SegmentAuthFlag)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	for _, s := range segments {
+		fmt.Printf("%s\t%s\n", s.Id, s.Name)
+	}
+}
+
+func HelpSegmentsList(args ...string) {
+	fmt.Println("Lists the segments of an application")
+	SegmentFlagSet.PrintDefaults()
+}
+
+func SegmentsNew(args ...string) {
+	SegmentFlagSet.Parse(args)
+	segment := gamethrive.Segment{
+		AppId: *SegmentAppIdFlag,
+		Name:  *SegmentNameFlag,
+	}
+	err := json.Unmarshal([]byte(*SegmentFiltersFlag), &segment.Filters)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	c := newClient()
+	err = c.Segments.New(&segment, *SegmentAuthFlag)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	fmt.Printf("Segment created correctly. Segment id is: \"%s\"\n", segment.Id)
+}
+
+func HelpSegmentsNew(args ...string) {
+	fmt.Println("Creates a segment")
+	SegmentFlagSet.PrintDefaults()
+}
+
+func SegmentsDelete(args ...string) {
+	SegmentFlagSet.Parse(args)
+	c := newClient()
+	err := c.Segments.Delete(*SegmentAppIdFlag, *SegmentIdFlag, *SegmentAuthFlag)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+	}
+}
+
+func HelpSegmentsDelete(args ...string) {
+	fmt.Println("Deletes a segment")
+	SegmentFlagSet.PrintDefaults()
+}
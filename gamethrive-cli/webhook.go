@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+
+	"../gamethrive/webhook"
+)
+
+var (
+	WebhookFlagSet            *flag.FlagSet
+	WebhookSecretFlag         *string
+	WebhookAddrFlag           *string
+	WebhookURLFlag            *string
+	WebhookAppIdFlag          *string
+	WebhookNotificationIdFlag *string
+	WebhookPlayerIdFlag       *string
+)
+
+func init() {
+	WebhookFlagSet = flag.NewFlagSet("webhook", flag.ContinueOnError)
+	WebhookSecretFlag = WebhookFlagSet.String("secret", "", "Secret used to sign webhook requests")
+	WebhookAddrFlag = WebhookFlagSet.String("addr", ":8080", "Address to listen on")
+	WebhookURLFlag = WebhookFlagSet.String("url", "http://localhost:8080/", "Url of the webhook receiver")
+	WebhookAppIdFlag = WebhookFlagSet.String("app_id", "", "Application key of the sample events")
+	WebhookNotificationIdFlag = WebhookFlagSet.String("notification_id", "sample-notification", "Notification id of the sample events")
+	WebhookPlayerIdFlag = WebhookFlagSet.String("player_id", "sample-player", "Player id of the sample events")
+}
+
+func WebhookListen(args ...string) {
+	WebhookFlagSet.Parse(args)
+	r, err := webhook.NewReceiver(*WebhookSecretFlag)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	r.OnDisplayed(func(e *webhook.DisplayedEvent) error { return printEvent(e) })
+	r.OnClicked(func(e *webhook.ClickedEvent) error { return printEvent(e) })
+	r.OnDismissed(func(e *webhook.DismissedEvent) error { return printEvent(e) })
+	err = http.ListenAndServe(*WebhookAddrFlag, r)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+	}
+}
+
+func HelpWebhookListen(args ...string) {
+	fmt.Println("Receives webhook events and prints them")
+	WebhookFlagSet.PrintDefaults()
+}
+
+func WebhookTest(args ...string) {
+	WebhookFlagSet.Parse(args)
+	events := webhook.SampleEvents(*WebhookAppIdFlag, *WebhookNotificationIdFlag, *WebhookPlayerIdFlag)
+	err := webhook.Post(nil, *WebhookURLFlag, *WebhookSecretFlag, events...)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	fmt.Printf("%d sample events delivered\n", len(events))
+}
+
+func HelpWebhookTest(args ...string) {
+	fmt.Println("Posts sample events to a webhook receiver")
+	WebhookFlagSet.PrintDefaults()
+}
+
+func printEvent(event interface{}) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -0,0 +1,281 @@
+package gamethrive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SegmentEngine evaluates segments and filters locally, to preview the
+// audience of a notification without sending it.
+type SegmentEngine struct {
+	segments map[string][]Filter
+	// Now is the reference time for session filters, defaults to time.Now.
+	Now func() time.Time
+}
+
+// Audience is the result of evaluating a notification target.
+type Audience struct {
+	Players []Player
+	Count   int
+	// Segments counts the matched players included by each segment.
+	Segments map[string]int
+}
+
+func NewSegmentEngine(segments []Segment) *SegmentEngine {
+	e := &SegmentEngine{segments: map[string][]Filter{}, Now: time.Now}
+	for _, s := range segments {
+		e.segments[s.Name] = s.Filters
+	}
+	return e
+}
+
+// InSegment reports whether player belongs to the named segment. The
+// builtin "All" segment contains every player.
+func (e *SegmentEngine) InSegment(player *Player, name string) (bool, error) {
+	if name == "All" {
+		return true, nil
+	}
+	filters, ok := e.segments[name]
+	if !ok {
+		return false, fmt.Errorf("Unknown segment %q", name)
+	}
+	return e.Match(player, filters)
+}
+
+// Match reports whether player satisfies filters. Filters are ANDed within
+// groups separated by Or filters, and an empty list matches everyone. An
+// Or at either end of the list, or following another Or, would leave an
+// empty group and is an error.
+func (e *SegmentEngine) Match(player *Player, filters []Filter) (bool, error) {
+	if len(filters) <= 0 {
+		return true, nil
+	}
+	for i, f := range filters {
+		if !isOr(f) {
+			continue
+		}
+		if i == 0 || i == len(filters)-1 || isOr(filters[i-1]) {
+			return false, fmt.Errorf("Invalid OR filter at position %d: it must separate two groups of filters", i+1)
+		}
+	}
+	now := e.Now()
+	group := true
+	for _, f := range filters {
+		if isOr(f) {
+			if group {
+				return true, nil
+			}
+			group = true
+			continue
+		}
+		if !group {
+			continue
+		}
+		ok, err := matchFilter(player, f, now)
+		if err != nil {
+			return false, err
+		}
+		group = ok
+	}
+	return group, nil
+}
+
+func isOr(f Filter) bool {
+	return strings.EqualFold(f.Operator, "OR")
+}
+
+// Evaluate returns the players targeted by notification: those in any
+// included segment, listed by id, device token or external user id, or
+// matching Filters, minus those in any excluded segment. When IsIOS or
+// IsAndroid are set, only players of those platforms are counted.
+func (e *SegmentEngine) Evaluate(notification *Notification, players []Player) (*Audience, error) {
+	ids := map[string]bool{}
+	for _, id := range notification.IncludedPlayerIds {
+		ids[id] = true
+	}
+	tokens := map[string]bool{}
+	for _, token := range notification.IncludedIOSTokens {
+		tokens[fmt.Sprintf("%d/%s", IOS, token)] = true
+	}
+	for _, token := range notification.IncludedAndroidRegIds {
+		tokens[fmt.Sprintf("%d/%s", Android, token)] = true
+	}
+	users := map[string]bool{}
+	for _, id := range notification.IncludedExternalUserIds {
+		users[id] = true
+	}
+	audience := &Audience{Segments: map[string]int{}}
+	for i := range players {
+		p := &players[i]
+		if !targetsPlatform(notification, p.DeviceType) {
+			continue
+		}
+		included := ids[p.Id] ||
+			len(p.Identifier) > 0 && tokens[fmt.Sprintf("%d/%s", p.DeviceType, p.Identifier)] ||
+			len(p.ExternalUserId) > 0 && users[p.ExternalUserId]
+		var segments []string
+		for _, name := range notification.IncludedSegments {
+			ok, err := e.InSegment(p, name)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				included = true
+				segments = append(segments, name)
+			}
+		}
+		if len(notification.Filters) > 0 {
+			ok, err := e.Match(p, notification.Filters)
+			if err != nil {
+				return nil, err
+			}
+			included = included || ok
+		}
+		if !included {
+			continue
+		}
+		excluded := false
+		for _, name := range notification.ExcludedSegments {
+			ok, err := e.InSegment(p, name)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				excluded = true
+				break
+			}
+		}
+		if excluded {
+			continue
+		}
+		audience.Players = append(audience.Players, *p)
+		for _, name := range segments {
+			audience.Segments[name]++
+		}
+	}
+	audience.Count = len(audience.Players)
+	return audience, nil
+}
+
+func targetsPlatform(notification *Notification, t DeviceType) bool {
+	if !notification.IsIOS && !notification.IsAndroid {
+		return true
+	}
+	switch t {
+	case IOS:
+		return notification.IsIOS
+	case Android, Amazon:
+		return notification.IsAndroid
+	}
+	return false
+}
+
+func matchFilter(player *Player, f Filter, now time.Time) (bool, error) {
+	switch f.Field {
+	case FieldLastSession:
+		return compareNumber(hoursSince(player.LastActive, now), f)
+	case FieldFirstSession:
+		return compareNumber(hoursSince(player.CreatedAt, now), f)
+	case FieldSessionCount:
+		return compareNumber(float64(player.SessionCount), f)
+	case FieldSessionTime:
+		return compareNumber(float64(player.Playtime), f)
+	case FieldAmountSpent:
+		return compareNumber(player.AmountSpent, f)
+	case FieldLanguage:
+		return compareString(player.Language, true, f)
+	case FieldAppVersion:
+		return compareString(player.GameVersion, true, f)
+	case FieldTag:
+		v, ok := player.Tags[f.Key]
+		if len(v) <= 0 {
+			ok = false
+		}
+		switch f.Relation {
+		case Exists:
+			return ok, nil
+		case NotExist:
+			return !ok, nil
+		case Greater, Less:
+			n, err := strconv.ParseFloat(v, 64)
+			if !ok || err != nil {
+				return false, nil
+			}
+			return compareNumber(n, f)
+		}
+		return compareString(v, ok, f)
+	}
+	return false, fmt.Errorf("Unknown filter field %q", f.Field)
+}
+
+func hoursSince(unixtime int, now time.Time) float64 {
+	return now.Sub(time.Unix(int64(unixtime), 0)).Hours()
+}
+
+func compareNumber(v float64, f Filter) (bool, error) {
+	n, err := strconv.ParseFloat(f.Value, 64)
+	if err != nil {
+		return false, fmt.Errorf("Invalid value %q for filter %s", f.Value, f.Field)
+	}
+	switch f.Relation {
+	case Greater:
+		return v > n, nil
+	case Less:
+		return v < n, nil
+	case Equal:
+		return v == n, nil
+	case NotEqual:
+		return v != n, nil
+	}
+	return false, fmt.Errorf("Invalid relation %q for filter %s", f.Relation, f.Field)
+}
+
+func compareString(v string, ok bool, f Filter) (bool, error) {
+	switch f.Relation {
+	case Equal:
+		return ok && v == f.Value, nil
+	case NotEqual:
+		return !ok || v != f.Value, nil
+	}
+	return false, fmt.Errorf("Invalid relation %q for filter %s", f.Relation, f.Field)
+}
+
+// ReadPlayers decodes an export of players, either a json array or one
+// json object per line.
+func ReadPlayers(r io.Reader) ([]Player, error) {
+	br := bufio.NewReader(r)
+	data, err := br.Peek(1)
+	for err == nil && len(bytes.TrimSpace(data)) <= 0 {
+		br.ReadByte()
+		data, err = br.Peek(1)
+	}
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var players []Player
+	dec := json.NewDecoder(br)
+	if data[0] == '[' {
+		err := dec.Decode(&players)
+		return players, err
+	}
+	for {
+		var p Player
+		err := dec.Decode(&p)
+		if err == io.EOF {
+			return players, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, p)
+	}
+}
@@ -0,0 +1,84 @@
+package gamethrive
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSegmentEngine_Evaluate(t *testing.T) {
+	now := time.Date(2015, 9, 24, 12, 0, 0, 0, time.UTC)
+	players := []Player{
+		{Id: "whale", DeviceType: IOS, Identifier: "tok", AmountSpent: 120, LastActive: int(now.Add(-2 * time.Hour).Unix()), Tags: map[string]string{"level": "40"}},
+		{Id: "casual", DeviceType: Android, ExternalUserId: "u-casual", AmountSpent: 0, LastActive: int(now.Add(-72 * time.Hour).Unix()), Tags: map[string]string{"level": "3"}},
+		{Id: "lapsed", DeviceType: Android, ExternalUserId: "u-lapsed", AmountSpent: 5, LastActive: int(now.Add(-500 * time.Hour).Unix()), Language: "es"},
+		{Id: "web", DeviceType: ChromeWeb, Tags: map[string]string{"level": "12"}},
+	}
+	engine := NewSegmentEngine([]Segment{
+		{Name: "Payers", Filters: []Filter{FieldFilter(FieldAmountSpent, Greater, "0")}},
+		{Name: "Inactive", Filters: []Filter{FieldFilter(FieldLastSession, Greater, "168")}},
+		{Name: "Veterans", Filters: []Filter{TagFilter("level", Greater, "30"), Or(), FieldFilter(FieldLanguage, Equal, "es")}},
+	})
+	engine.Now = func() time.Time { return now }
+
+	tests := []struct {
+		notification Notification
+		want         []string
+	}{
+		{Notification{IncludedSegments: []string{"All"}}, []string{"whale", "casual", "lapsed", "web"}},
+		{Notification{IncludedSegments: []string{"Payers"}, ExcludedSegments: []string{"Inactive"}}, []string{"whale"}},
+		{Notification{IncludedSegments: []string{"Veterans"}}, []string{"whale", "lapsed"}},
+		{Notification{IncludedSegments: []string{"All"}, IsAndroid: true}, []string{"casual", "lapsed"}},
+		{Notification{IncludedPlayerIds: []string{"casual"}, Filters: []Filter{TagFilter("level", Exists, "")}}, []string{"whale", "casual", "web"}},
+		{Notification{IncludedIOSTokens: []string{"tok"}, IncludedAndroidRegIds: []string{"tok"}}, []string{"whale"}},
+		{Notification{IncludedExternalUserIds: []string{"u-casual", "u-lapsed"}, ExcludedSegments: []string{"Inactive"}}, []string{"casual"}},
+	}
+	for _, test := range tests {
+		audience, err := engine.Evaluate(&test.notification, players)
+		if err != nil {
+			t.Errorf("Evaluate(%+v) error = %v", test.notification, err)
+			continue
+		}
+		var got []string
+		for _, p := range audience.Players {
+			got = append(got, p.Id)
+		}
+		if strings.Join(got, ",") != strings.Join(test.want, ",") || audience.Count != len(test.want) {
+			t.Errorf("Evaluate(%+v) = %v (%d), want %v", test.notification, got, audience.Count, test.want)
+		}
+	}
+	if _, err := engine.Evaluate(&Notification{IncludedSegments: []string{"Missing"}}, players); err == nil {
+		t.Error("Expected error for unknown segment")
+	}
+}
+
+func TestSegmentEngine_Match_emptyGroup(t *testing.T) {
+	engine := NewSegmentEngine(nil)
+	level := TagFilter("level", Greater, "30")
+	tests := [][]Filter{
+		{Or(), level},
+		{level, Or()},
+		{level, Or(), Or(), level},
+		{Or()},
+	}
+	for _, filters := range tests {
+		if _, err := engine.Match(&Player{}, filters); err == nil {
+			t.Errorf("Match(%+v) expected error for an empty OR group", filters)
+		}
+	}
+	if ok, err := engine.Match(&Player{Tags: map[string]string{"level": "40"}}, []Filter{level, Or(), level}); !ok || err != nil {
+		t.Errorf("Match of two groups = %v, %v", ok, err)
+	}
+}
+
+func TestReadPlayers(t *testing.T) {
+	for _, in := range []string{
+		`[{"id":"a"},{"id":"b"}]`,
+		"{\"id\":\"a\"}\n{\"id\":\"b\"}\n",
+	} {
+		players, err := ReadPlayers(strings.NewReader(in))
+		if err != nil || len(players) != 2 || players[1].Id != "b" {
+			t.Errorf("ReadPlayers(%q) = %v, %v", in, players, err)
+		}
+	}
+}
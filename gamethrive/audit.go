@@ -0,0 +1,246 @@
+package gamethrive
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes a mutating API call. The payload is only kept as
+// a hash, and auth keys are never recorded.
+type AuditRecord struct {
+	Time        time.Time `json:"time"`
+	Operator    string    `json:"operator,omitempty"`
+	Method      string    `json:"method"`
+	Path        string    `json:"path"`
+	PayloadHash string    `json:"payload_hash,omitempty"`
+	Status      int       `json:"status,omitempty"`
+	ResponseId  string    `json:"response_id,omitempty"`
+	Outcome     string    `json:"outcome"`
+	Error       string    `json:"error,omitempty"`
+	// Previous is the signature of the record before this one, chaining
+	// the log so that removed or reordered records are detected. Records
+	// removed from the end of the log leave a valid chain, and are only
+	// noticed by comparing the last signature with a copy kept elsewhere.
+	Previous  string `json:"previous,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditSink receives a record for every mutating call made by a Client.
+type AuditSink interface {
+	Record(record *AuditRecord) error
+}
+
+var ErrAuditTampered = errors.New("Audit log was modified")
+
+// AuditLog is an AuditSink appending records, signed with HMAC-SHA256, to a
+// json lines file.
+type AuditLog struct {
+	key  []byte
+	mu   sync.Mutex
+	file *os.File
+	last string
+}
+
+// OpenAuditLog opens or creates the log at path, checking the signatures
+// of its existing records. A record cut short at the end of the log, as
+// left by a crash while it was written, is removed. When the log fails the
+// check otherwise, it is still opened, chaining new records from the last
+// valid one, and returned along with the error.
+func OpenAuditLog(path string, key []byte) (*AuditLog, error) {
+	if len(key) <= 0 {
+		return nil, errors.New("Audit key is required")
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	records, valid, err := readAuditLog(file, key)
+	repaired, rerr := repairAuditTail(file, valid)
+	if rerr != nil {
+		file.Close()
+		return nil, rerr
+	}
+	if repaired {
+		err = nil
+	}
+	l := &AuditLog{key: key, file: file}
+	if len(records) > 0 {
+		l.last = records[len(records)-1].Signature
+	}
+	return l, err
+}
+
+// repairAuditTail makes the log end right after its last valid record, at
+// offset, when all that follows it is a single incomplete record or the
+// newline of that record is missing. It reports whether the log was fixed.
+func repairAuditTail(file *os.File, offset int64) (bool, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.Size() < offset {
+		_, err := file.Write([]byte("\n"))
+		return err == nil, err
+	}
+	tail := make([]byte, info.Size()-offset)
+	if _, err := file.ReadAt(tail, offset); err != nil {
+		return false, err
+	}
+	tail = bytes.TrimSpace(tail)
+	var record AuditRecord
+	if len(tail) <= 0 || bytes.Contains(tail, []byte("\n")) || json.Unmarshal(tail, &record) == nil {
+		return false, nil
+	}
+	return true, file.Truncate(offset)
+}
+
+// Record signs record, chaining it to the previous one, and appends it to
+// the log, syncing the file before returning.
+func (l *AuditLog) Record(record *AuditRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	record.Previous = l.last
+	record.Signature = ""
+	record.Signature = signAuditRecord(record, l.key)
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	l.last = record.Signature
+	return nil
+}
+
+// Last returns the signature of the latest record, to be kept outside the
+// log to detect records removed from its end.
+func (l *AuditLog) Last() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.last
+}
+
+func (l *AuditLog) Close() error {
+	return l.file.Close()
+}
+
+func signAuditRecord(record *AuditRecord, key []byte) string {
+	r := *record
+	r.Signature = ""
+	data, _ := json.Marshal(r)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ReadAuditLog reads every record of the log at path, verifying their
+// signatures and chaining. It returns ErrAuditTampered, along with the
+// records read until then, when the log was modified other than by
+// removing records from its end.
+func ReadAuditLog(path string, key []byte) ([]AuditRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	records, _, err := readAuditLog(file, key)
+	return records, err
+}
+
+// readAuditLog also returns the offset right after the last valid record.
+func readAuditLog(r io.Reader, key []byte) ([]AuditRecord, int64, error) {
+	var records []AuditRecord
+	var offset, valid int64
+	previous := ""
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		offset += int64(len(scanner.Bytes())) + 1
+		if len(bytes.TrimSpace(scanner.Bytes())) <= 0 {
+			continue
+		}
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return records, valid, fmt.Errorf("Invalid audit record at line %d: %s", n, err.Error())
+		}
+		if record.Previous != previous || !hmac.Equal([]byte(record.Signature), []byte(signAuditRecord(&record, key))) {
+			return records, valid, ErrAuditTampered
+		}
+		previous = record.Signature
+		records = append(records, record)
+		valid = offset
+	}
+	return records, valid, scanner.Err()
+}
+
+// AuditQuery selects audit records. Empty fields match every record.
+type AuditQuery struct {
+	Operator string
+	Method   string
+	// Path matches records whose path starts with it.
+	Path    string
+	Outcome string
+	Since   time.Time
+	Until   time.Time
+}
+
+func (q *AuditQuery) Match(record *AuditRecord) bool {
+	switch {
+	case len(q.Operator) > 0 && record.Operator != q.Operator,
+		len(q.Method) > 0 && !strings.EqualFold(record.Method, q.Method),
+		len(q.Path) > 0 && !strings.HasPrefix(record.Path, q.Path),
+		len(q.Outcome) > 0 && record.Outcome != q.Outcome,
+		!q.Since.IsZero() && record.Time.Before(q.Since),
+		!q.Until.IsZero() && !record.Time.Before(q.Until):
+		return false
+	}
+	return true
+}
+
+// audit records a mutating call with the client AuditSink. body is the
+// request payload and response the body of a successful response.
+func (c *Client) audit(method, path string, body []byte, status int, response []byte, err error) error {
+	record := &AuditRecord{
+		Time:     time.Now().UTC(),
+		Operator: c.Operator,
+		Method:   method,
+		Path:     path,
+		Status:   status,
+		Outcome:  AuditSuccess,
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		sum := sha256.Sum256(body)
+		record.PayloadHash = hex.EncodeToString(sum[:])
+	}
+	if err != nil {
+		record.Outcome = AuditFailure
+		record.Error = err.Error()
+	}
+	var res struct {
+		Id string `json:"id"`
+	}
+	if json.Unmarshal(response, &res) == nil {
+		record.ResponseId = res.Id
+	}
+	return c.Audit.Record(record)
+}
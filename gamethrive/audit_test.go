@@ -0,0 +1,122 @@
+package gamethrive
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClient_Audit(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"n1","recipients":3}`)
+	})
+	mux.HandleFunc("/players/p1/on_purchase", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"errors":["Invalid amount"]}`)
+	})
+	path := filepath.Join(t.TempDir(), "audit.log")
+	key := []byte("secret")
+	log, err := OpenAuditLog(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Audit = log
+	client.Operator = "alice"
+
+	if _, err := client.Notifications.New(&Notification{AppId: "app", Contents: map[string]string{"en": "Hi"}}, "key"); err != nil {
+		t.Fatal(err)
+	}
+	client.Players.UpdateAmount("p1", -1)
+	last := log.Last()
+	log.Close()
+
+	records, err := ReadAuditLog(path, key)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("ReadAuditLog() = %+v, %v", records, err)
+	}
+	if r := records[0]; r.Operator != "alice" || r.ResponseId != "n1" || r.Outcome != AuditSuccess || len(r.PayloadHash) != 64 {
+		t.Errorf("records[0] = %+v", r)
+	}
+	if r := records[1]; r.Outcome != AuditFailure || r.Status != http.StatusBadRequest || r.Previous != records[0].Signature || r.Signature != last {
+		t.Errorf("records[1] = %+v", r)
+	}
+	q := AuditQuery{Outcome: AuditFailure}
+	if q.Match(&records[0]) || !q.Match(&records[1]) {
+		t.Errorf("AuditQuery{Outcome: failure} matched wrong records")
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	ioutil.WriteFile(path, []byte(strings.Replace(string(data), "alice", "bob", 1)), 0600)
+	if _, err := ReadAuditLog(path, key); err != ErrAuditTampered {
+		t.Errorf("ReadAuditLog() of a modified log = %v, want ErrAuditTampered", err)
+	}
+}
+
+func TestOpenAuditLog_resume(t *testing.T) {
+	key := []byte("secret")
+	record := func(path string) *AuditLog {
+		log, err := OpenAuditLog(path, key)
+		if err != nil {
+			t.Fatalf("OpenAuditLog() error = %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if err := log.Record(&AuditRecord{Method: "POST", Path: "/api/v1/notifications", Outcome: AuditSuccess}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		log.Close()
+		return log
+	}
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	record(path)
+	data, _ := ioutil.ReadFile(path)
+	ioutil.WriteFile(path, data[:len(data)-20], 0600)
+	record(path)
+	if records, err := ReadAuditLog(path, key); err != nil || len(records) != 3 {
+		t.Errorf("ReadAuditLog() after a cut record = %d records, %v, want 3", len(records), err)
+	}
+
+	path = filepath.Join(t.TempDir(), "audit.log")
+	record(path)
+	data, _ = ioutil.ReadFile(path)
+	ioutil.WriteFile(path, []byte(strings.Replace(string(data), "POST", "PUT", 1)), 0600)
+	log, err := OpenAuditLog(path, key)
+	if log == nil || err != ErrAuditTampered {
+		t.Fatalf("OpenAuditLog() of a modified log = %v, %v, want the log and ErrAuditTampered", log, err)
+	}
+	defer log.Close()
+	if log.Last() != "" {
+		t.Errorf("Last() = %q, want the chain to restart before the modified record", log.Last())
+	}
+}
+
+type failingSink struct{}
+
+func (failingSink) Record(record *AuditRecord) error {
+	return errors.New("disk full")
+}
+
+func TestClient_Audit_failure(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"n1","recipients":3}`)
+	})
+	client.Audit = failingSink{}
+	var auditErr error
+	client.OnAuditError = func(err error) { auditErr = err }
+	n := &Notification{AppId: "app", Contents: map[string]string{"en": "Hi"}}
+	if _, err := client.Notifications.New(n, "key"); err != nil || n.Id != "n1" {
+		t.Errorf("New() with a failing audit sink = %v, id %q, want it delivered", err, n.Id)
+	}
+	if auditErr == nil {
+		t.Error("OnAuditError was not called")
+	}
+}
@@ -0,0 +1,245 @@
+package gamethrive
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// PlayerBatcher coalesces Players.Session, Players.UpdateAmount and
+// Players.Playtime events of each player and sends them together when
+// Window elapses or MaxEvents are pending. Within a batch purchase amounts
+// and playtime seconds are summed, and session updates are merged into a
+// single call (later fields win, tags are merged), so the session count is
+// incremented once per batch. Every event reports its outcome through its
+// optional done callback, which is called before returning when the event
+// is rejected. Callbacks run without any batcher lock held, once their
+// batch is delivered, so they may queue events or call Flush.
+type PlayerBatcher struct {
+	c *Client
+
+	// Window is how long events are held before being sent, defaults to
+	// five seconds.
+	Window time.Duration
+	// MaxEvents pending that trigger an immediate flush, defaults to 1000.
+	MaxEvents int
+	// Workers is the number of players sent concurrently, defaults to 4.
+	Workers int
+
+	mu      sync.Mutex
+	pending map[string]*playerBatch
+	order   []string
+	events  int
+	timer   *time.Timer
+	sending int
+	idle    *sync.Cond
+	closed  bool
+}
+
+type playerBatch struct {
+	session      *Player
+	sessionDone  []func(error)
+	amount       float64
+	amountDone   []func(error)
+	playtime     int
+	state        PlaytimeState
+	playtimeDone []func(error)
+}
+
+var ErrBatcherClosed = errors.New("Batcher is closed")
+
+func NewPlayerBatcher(client *Client) *PlayerBatcher {
+	b := &PlayerBatcher{
+		c:         client,
+		Window:    5 * time.Second,
+		MaxEvents: 1000,
+		Workers:   4,
+		pending:   map[string]*playerBatch{},
+	}
+	b.idle = sync.NewCond(&b.mu)
+	return b
+}
+
+// Session queues a session update for player.
+func (b *PlayerBatcher) Session(player *Player, done func(error)) {
+	b.add(player.Id, done, func(pb *playerBatch) {
+		if pb.session == nil {
+			pb.session = &Player{Id: player.Id}
+		}
+		mergePlayer(pb.session, player)
+		pb.sessionDone = append(pb.sessionDone, done)
+	})
+}
+
+// UpdateAmount queues a purchase of amount USD for a player.
+func (b *PlayerBatcher) UpdateAmount(playerId string, amount float64, done func(error)) {
+	b.add(playerId, done, func(pb *playerBatch) {
+		pb.amount += amount
+		pb.amountDone = append(pb.amountDone, done)
+	})
+}
+
+// Playtime queues time seconds of playtime for a player. The batch is sent
+// with the state of the last queued event.
+func (b *PlayerBatcher) Playtime(playerId string, state PlaytimeState, time int, done func(error)) {
+	b.add(playerId, done, func(pb *playerBatch) {
+		pb.playtime += time
+		pb.state = state
+		pb.playtimeDone = append(pb.playtimeDone, done)
+	})
+}
+
+// Flush sends every pending event and waits until they are delivered. The
+// done callbacks run once their batch is delivered, possibly after Flush
+// returns.
+func (b *PlayerBatcher) Flush() {
+	b.mu.Lock()
+	b.flushLocked()
+	b.wait()
+	b.mu.Unlock()
+}
+
+// Close flushes pending events and rejects any later one.
+func (b *PlayerBatcher) Close() {
+	b.mu.Lock()
+	b.closed = true
+	b.flushLocked()
+	b.wait()
+	b.mu.Unlock()
+}
+
+// wait blocks until no flush is in progress. It must be called with b.mu
+// held.
+func (b *PlayerBatcher) wait() {
+	for b.sending > 0 {
+		b.idle.Wait()
+	}
+}
+
+func (b *PlayerBatcher) add(playerId string, done func(error), merge func(*playerBatch)) {
+	if len(playerId) <= 0 {
+		notify(done, errors.New("Player id is required"))
+		return
+	}
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		notify(done, ErrBatcherClosed)
+		return
+	}
+	defer b.mu.Unlock()
+	pb, ok := b.pending[playerId]
+	if !ok {
+		pb = new(playerBatch)
+		b.pending[playerId] = pb
+		b.order = append(b.order, playerId)
+	}
+	merge(pb)
+	b.events++
+	if b.MaxEvents > 0 && b.events >= b.MaxEvents {
+		b.flushLocked()
+		return
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.Window, func() {
+			b.mu.Lock()
+			b.flushLocked()
+			b.mu.Unlock()
+		})
+	}
+}
+
+// flushLocked hands the pending batches to a background flush. It must be
+// called with b.mu held.
+func (b *PlayerBatcher) flushLocked() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.order) <= 0 {
+		return
+	}
+	pending, order := b.pending, b.order
+	b.pending, b.order, b.events = map[string]*playerBatch{}, nil, 0
+	b.sending++
+	go func() {
+		results := b.send(pending, order)
+		b.mu.Lock()
+		b.sending--
+		b.idle.Broadcast()
+		b.mu.Unlock()
+		for _, notify := range results {
+			notify()
+		}
+	}()
+}
+
+// send delivers the batches and returns the calls reporting their results
+// to the done callbacks.
+func (b *PlayerBatcher) send(pending map[string]*playerBatch, order []string) []func() {
+	workers := b.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	queue := make(chan string)
+	var mu sync.Mutex
+	var results []func()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range queue {
+				r := b.sendPlayer(id, pending[id])
+				mu.Lock()
+				results = append(results, r...)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, id := range order {
+		queue <- id
+	}
+	close(queue)
+	wg.Wait()
+	return results
+}
+
+func (b *PlayerBatcher) sendPlayer(playerId string, pb *playerBatch) []func() {
+	var results []func()
+	if pb.session != nil {
+		err := b.c.Players.Session(pb.session)
+		results = append(results, func() { notifyAll(pb.sessionDone, err) })
+	}
+	if len(pb.amountDone) > 0 {
+		err := b.c.Players.UpdateAmount(playerId, pb.amount)
+		results = append(results, func() { notifyAll(pb.amountDone, err) })
+	}
+	if len(pb.playtimeDone) > 0 {
+		err := b.c.Players.Playtime(playerId, pb.state, pb.playtime)
+		results = append(results, func() { notifyAll(pb.playtimeDone, err) })
+	}
+	return results
+}
+
+// mergePlayer copies the fields PlayerChanges finds set in src into dst,
+// merging tags. As IOS is the zero device type, it never replaces another
+// one.
+func mergePlayer(dst, src *Player) {
+	changes, _ := PlayerChanges(new(Player), src)
+	data, _ := json.Marshal(changes)
+	json.Unmarshal(data, dst)
+}
+
+func notify(done func(error), err error) {
+	if done != nil {
+		done(err)
+	}
+}
+
+func notifyAll(done []func(error), err error) {
+	for _, d := range done {
+		notify(d, err)
+	}
+}
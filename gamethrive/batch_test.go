@@ -0,0 +1,121 @@
+package gamethrive
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPlayerBatcher_Flush(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	var mu sync.Mutex
+	bodies := map[string]map[string]interface{}{}
+	record := func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		bodies[r.URL.Path] = body
+		mu.Unlock()
+		w.Write([]byte(`{"success":true}`))
+	}
+	mux.HandleFunc("/players/p1/on_session", record)
+	mux.HandleFunc("/players/p1/on_purchase", record)
+	mux.HandleFunc("/players/p1/on_focus", record)
+
+	b := NewPlayerBatcher(client)
+	b.Window = time.Hour
+	var results sync.WaitGroup
+	results.Add(6)
+	done := func(err error) {
+		if err != nil {
+			t.Errorf("Event error = %v", err)
+		}
+		results.Done()
+	}
+	b.Session(&Player{Id: "p1", DeviceType: Android, Language: "en", Tags: map[string]string{"level": "1"}}, done)
+	b.Session(&Player{Id: "p1", Tags: map[string]string{"guild": "red"}}, done)
+	b.UpdateAmount("p1", 0.99, done)
+	b.UpdateAmount("p1", 1.01, done)
+	b.Playtime("p1", Ping, 30, done)
+	b.Playtime("p1", Suspend, 15, done)
+	b.Flush()
+	results.Wait()
+
+	tags := bodies["/players/p1/on_session"]["tags"]
+	if want := map[string]interface{}{"level": "1", "guild": "red"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("Session tags = %v, want %v", tags, want)
+	}
+	if device := bodies["/players/p1/on_session"]["device_type"]; device != 1.0 {
+		t.Errorf("Session device type = %v, want android", device)
+	}
+	if lang := bodies["/players/p1/on_session"]["language"]; lang != "en" {
+		t.Errorf("Session language = %v, want en", lang)
+	}
+	if amount := bodies["/players/p1/on_purchase"]["amount"]; amount != 2.0 {
+		t.Errorf("Purchase amount = %v, want 2", amount)
+	}
+	focus := bodies["/players/p1/on_focus"]
+	if focus["active_time"] != 45.0 || focus["state"] != "suspend" {
+		t.Errorf("Playtime body = %v, want 45 seconds suspend", focus)
+	}
+}
+
+func TestPlayerBatcher_MaxEvents(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	sent := make(chan struct{}, 1)
+	mux.HandleFunc("/players/p1/on_purchase", func(w http.ResponseWriter, r *http.Request) {
+		sent <- struct{}{}
+	})
+	b := NewPlayerBatcher(client)
+	b.Window = time.Hour
+	b.MaxEvents = 2
+	b.UpdateAmount("p1", 1, nil)
+	b.UpdateAmount("p1", 1, nil)
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Error("Batch was not flushed after MaxEvents")
+	}
+	b.Close()
+	var err error
+	b.UpdateAmount("p1", 1, func(e error) { err = e })
+	if err != ErrBatcherClosed {
+		t.Errorf("Event after Close error = %v, want %v", err, ErrBatcherClosed)
+	}
+}
+
+func TestPlayerBatcher_Flush_fromCallback(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	mux.HandleFunc("/players/p1/on_purchase", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":true}`))
+	})
+	b := NewPlayerBatcher(client)
+	b.Window = time.Hour
+	flushed := make(chan struct{})
+	b.UpdateAmount("p1", 1, func(err error) {
+		b.UpdateAmount("p1", 1, nil)
+		b.Flush()
+		close(flushed)
+	})
+	b.Flush()
+	select {
+	case <-flushed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Flush from a done callback did not return")
+	}
+}
+
+func TestMergePlayer(t *testing.T) {
+	dst := &Player{Id: "p1", DeviceType: Android, Language: "en", Tags: map[string]string{"level": "1"}}
+	mergePlayer(dst, &Player{Id: "p1", GameVersion: "1.2", SessionCount: 3, Tags: map[string]string{"guild": "red"}})
+	want := &Player{Id: "p1", DeviceType: Android, Language: "en", GameVersion: "1.2", SessionCount: 3, Tags: map[string]string{"level": "1", "guild": "red"}}
+	if !reflect.DeepEqual(dst, want) {
+		t.Errorf("mergePlayer() = %+v, want %+v", dst, want)
+	}
+}
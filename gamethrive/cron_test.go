@@ -0,0 +1,81 @@
+package gamethrive
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronSpec_Next(t *testing.T) {
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"0 18 * * fri", time.Date(2015, 9, 24, 12, 0, 0, 0, time.UTC), time.Date(2015, 9, 25, 18, 0, 0, 0, time.UTC)},
+		{"0 18 * * fri", time.Date(2015, 9, 25, 18, 0, 0, 0, time.UTC), time.Date(2015, 10, 2, 18, 0, 0, 0, time.UTC)},
+		{"*/15 9-17 * * 1-5", time.Date(2015, 9, 26, 12, 0, 0, 0, time.UTC), time.Date(2015, 9, 28, 9, 0, 0, 0, time.UTC)},
+		{"30 8 1 jan,jul *", time.Date(2015, 9, 24, 12, 0, 0, 0, time.UTC), time.Date(2016, 1, 1, 8, 30, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2015, 9, 24, 12, 0, 0, 0, time.UTC), time.Date(2015, 9, 25, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		c, err := ParseCron(test.spec, time.UTC)
+		if err != nil {
+			t.Errorf("ParseCron(%q) error = %v", test.spec, err)
+			continue
+		}
+		if got := c.Next(test.from); !got.Equal(test.want) {
+			t.Errorf("ParseCron(%q).Next(%v) = %v, want %v", test.spec, test.from, got, test.want)
+		}
+	}
+}
+
+func TestParseCron_invalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * * * funday", "5-1 * * * *", "*/0 * * * *"} {
+		if _, err := ParseCron(spec, time.UTC); err == nil {
+			t.Errorf("ParseCron(%q) expected error", spec)
+		}
+	}
+}
+
+func TestParseCronField(t *testing.T) {
+	tests := []struct {
+		field int
+		str   string
+		want  []int
+	}{
+		{0, "*/15", []int{0, 15, 30, 45}},
+		{0, "5,10-12", []int{5, 10, 11, 12}},
+		{0, "50/5", []int{50, 55}},
+		{1, "9-17/4", []int{9, 13, 17}},
+		{2, "31", []int{31}},
+		{3, "JAN,jul-aug", []int{1, 7, 8}},
+		{4, "mon-fri", []int{1, 2, 3, 4, 5}},
+		{4, "sat,7", []int{6, 7}},
+	}
+	for _, test := range tests {
+		var want uint64
+		for _, v := range test.want {
+			want |= 1 << uint(v)
+		}
+		got, err := parseCronField(test.str, cronFields[test.field])
+		if err != nil || got != want {
+			t.Errorf("parseCronField(%q) = %b, %v, want %b", test.str, got, err, want)
+		}
+	}
+	for _, str := range []string{"", "a-b", "1-", "0", "32", "3/x", "1/-1"} {
+		if _, err := parseCronField(str, cronFields[2]); err == nil {
+			t.Errorf("parseCronField(%q) expected error", str)
+		}
+	}
+}
+
+func TestParseCron_sunday(t *testing.T) {
+	c, err := ParseCron("0 12 * * 7", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2015, 9, 24, 12, 0, 0, 0, time.UTC)
+	if got, want := c.Next(from), time.Date(2015, 9, 27, 12, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next(%v) = %v, want %v", from, got, want)
+	}
+}
@@ -0,0 +1,220 @@
+package gamethrive
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrDuplicateNotification = errors.New("Duplicate notification")
+
+// DedupRecord is a notification remembered by its content hash, with its
+// requested delivery time, if any.
+type DedupRecord struct {
+	Id         string     `json:"id"`
+	Recipients int        `json:"recipients"`
+	SentAt     time.Time  `json:"sent_at"`
+	SendAfter  *time.Time `json:"send_after,omitempty"`
+}
+
+// DedupStore keeps the notifications sent by content hash.
+type DedupStore interface {
+	Get(hash string) (DedupRecord, bool, error)
+	Put(hash string, record DedupRecord) error
+	// Expire forgets the records sent before a time.
+	Expire(before time.Time) error
+}
+
+// DedupGuard stops Notifications.New from sending the same notification
+// twice within Window. Notifications are compared without their delivery
+// time, so relative send times such as "in 2h" match, but both must be
+// sent immediately or scheduled within Window of each other. Set it as
+// Client.Dedup.
+type DedupGuard struct {
+	Store  DedupStore
+	Window time.Duration
+	Now    func() time.Time
+
+	mu       sync.Mutex
+	inflight map[string]chan struct{}
+}
+
+// NewDedupGuard creates a guard, keeping records in memory when store is
+// nil.
+func NewDedupGuard(store DedupStore, window time.Duration) *DedupGuard {
+	if store == nil {
+		store = NewMemoryDedupStore()
+	}
+	return &DedupGuard{
+		Store:    store,
+		Window:   window,
+		Now:      time.Now,
+		inflight: map[string]chan struct{}{},
+	}
+}
+
+// NotificationHash returns the hash identifying a notification: its
+// application, contents, headings, buttons, data, SendAfter and target.
+// The order of target lists does not change the hash. DedupGuard hashes
+// notifications with SendAfter cleared and compares send times apart.
+func NotificationHash(n *Notification) string {
+	sorted := func(s []string) []string {
+		s = append([]string(nil), s...)
+		sort.Strings(s)
+		return s
+	}
+	key := struct {
+		AppId                 string
+		IsIOS                 bool
+		IsAndroid             bool
+		Contents              map[string]string
+		Headings              map[string]string
+		Buttons               []Button
+		Data                  map[string]string
+		URL                   string
+		SendAfter             *SendTime
+		IncludedSegments      []string
+		ExcludedSegments      []string
+		IncludedPlayerIds     []string
+		IncludedIOSTokens     []string
+		IncludedAndroidRegIds []string
+		IncludedExternalIds   []string
+		Filters               []Filter
+	}{
+		n.AppId, n.IsIOS, n.IsAndroid, n.Contents, n.Headings, n.Buttons, n.Data, n.URL, n.SendAfter,
+		sorted(n.IncludedSegments),
+		sorted(n.ExcludedSegments),
+		sorted(n.IncludedPlayerIds),
+		sorted(n.IncludedIOSTokens),
+		sorted(n.IncludedAndroidRegIds),
+		sorted(n.IncludedExternalUserIds),
+		n.Filters,
+	}
+	data, _ := json.Marshal(key)
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// send calls f unless a notification with the same hash was sent within
+// the window, in which case the original id and recipients are returned
+// along with ErrDuplicateNotification. Concurrent sends of the same
+// notification wait for the first one.
+func (g *DedupGuard) send(notification *Notification, f func() (int, error)) (int, error) {
+	unscheduled := *notification
+	unscheduled.SendAfter = nil
+	hash := NotificationHash(&unscheduled)
+	var sendAfter *time.Time
+	if notification.SendAfter != nil {
+		t := notification.SendAfter.Time
+		sendAfter = &t
+	}
+	for {
+		g.mu.Lock()
+		wait, busy := g.inflight[hash]
+		if !busy {
+			g.inflight[hash] = make(chan struct{})
+		}
+		g.mu.Unlock()
+		if !busy {
+			break
+		}
+		<-wait
+	}
+	defer func() {
+		g.mu.Lock()
+		close(g.inflight[hash])
+		delete(g.inflight, hash)
+		g.mu.Unlock()
+	}()
+
+	now := g.Now()
+	record, ok, err := g.Store.Get(hash)
+	if err != nil {
+		return 0, err
+	}
+	if ok && now.Sub(record.SentAt) < g.Window && g.sameSendTime(record.SendAfter, sendAfter) {
+		notification.Id = record.Id
+		return record.Recipients, ErrDuplicateNotification
+	}
+	recipients, err := f()
+	if err != nil {
+		return recipients, err
+	}
+	if err := g.Store.Expire(now.Add(-g.Window)); err != nil {
+		return recipients, err
+	}
+	return recipients, g.Store.Put(hash, DedupRecord{Id: notification.Id, Recipients: recipients, SentAt: now, SendAfter: sendAfter})
+}
+
+// sameSendTime reports whether two delivery times are both immediate or
+// within Window of each other.
+func (g *DedupGuard) sameSendTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	d := a.Sub(*b)
+	if d < 0 {
+		d = -d
+	}
+	return d < g.Window
+}
+
+// MemoryDedupStore is a DedupStore holding the records of the notifications
+// sent within the guard window. Stores opened with NewFileDedupStore let
+// separate runs of a sender recognize each other's notifications.
+type MemoryDedupStore struct {
+	path    string
+	mu      sync.Mutex
+	records map[string]DedupRecord
+}
+
+func NewMemoryDedupStore() *MemoryDedupStore {
+	return &MemoryDedupStore{records: map[string]DedupRecord{}}
+}
+
+// NewFileDedupStore opens the records kept at path, rewriting the file on
+// every Put. A missing file starts an empty store.
+func NewFileDedupStore(path string) (*MemoryDedupStore, error) {
+	s := NewMemoryDedupStore()
+	s.path = path
+	if err := loadJSONFile(path, "dedup store", &s.records); err != nil {
+		return nil, err
+	}
+	if s.records == nil {
+		s.records = map[string]DedupRecord{}
+	}
+	return s, nil
+}
+
+func (s *MemoryDedupStore) Get(hash string) (DedupRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[hash]
+	return record, ok, nil
+}
+
+func (s *MemoryDedupStore) Put(hash string, record DedupRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[hash] = record
+	return s.save()
+}
+
+func (s *MemoryDedupStore) Expire(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, record := range s.records {
+		if record.SentAt.Before(before) {
+			delete(s.records, hash)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryDedupStore) save() error {
+	return saveJSONFile(s.path, s.records)
+}
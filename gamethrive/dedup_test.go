@@ -0,0 +1,124 @@
+package gamethrive
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDedupGuard(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	var mu sync.Mutex
+	sent := 0
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sent++
+		id := sent
+		mu.Unlock()
+		fmt.Fprintf(w, `{"id":"n%d","recipients":10}`, id)
+	})
+	now := time.Date(2015, 9, 24, 12, 0, 0, 0, time.UTC)
+	client.Dedup = NewDedupGuard(nil, 10*time.Minute)
+	client.Dedup.Now = func() time.Time { return now }
+	newNotification := func() *Notification {
+		return &Notification{AppId: "app", Contents: map[string]string{"en": "Hi"}, IncludedPlayerIds: []string{"b", "a"}}
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			n := newNotification()
+			_, errs[i] = client.Notifications.New(n, "")
+			if n.Id != "n1" {
+				t.Errorf("Id = %q, want n1", n.Id)
+			}
+		}(i)
+	}
+	wg.Wait()
+	duplicates := 0
+	for _, err := range errs {
+		if err == ErrDuplicateNotification {
+			duplicates++
+		}
+	}
+	if sent != 1 || duplicates != 3 {
+		t.Fatalf("sent %d notifications, %d duplicates", sent, duplicates)
+	}
+
+	n := newNotification()
+	n.IncludedPlayerIds = []string{"a", "b"}
+	if recipients, err := client.Notifications.New(n, ""); err != ErrDuplicateNotification || recipients != 10 {
+		t.Errorf("New() with reordered target = %d, %v", recipients, err)
+	}
+	now = now.Add(10 * time.Minute)
+	if _, err := client.Notifications.New(newNotification(), ""); err != nil || sent != 2 {
+		t.Errorf("New() after the window = %v, sent %d", err, sent)
+	}
+}
+
+func TestDedupGuard_sendAfter(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	sent := 0
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		fmt.Fprintf(w, `{"id":"n%d","recipients":10}`, sent)
+	})
+	now := time.Date(2015, 9, 24, 12, 0, 0, 0, time.UTC)
+	client.Dedup = NewDedupGuard(nil, 10*time.Minute)
+	client.Dedup.Now = func() time.Time { return now }
+	in := func(d time.Duration) *Notification {
+		n := &Notification{AppId: "app", Contents: map[string]string{"en": "Hi"}}
+		if d > 0 {
+			n.SendAfter = &SendTime{now.Add(d)}
+		}
+		return n
+	}
+
+	tests := []struct {
+		notification *Notification
+		want         error
+	}{
+		{in(2 * time.Hour), nil},
+		// "in 2h" retried a minute later.
+		{in(2*time.Hour + time.Minute), ErrDuplicateNotification},
+		{in(5 * time.Hour), nil},
+		{in(0), nil},
+		{in(0), ErrDuplicateNotification},
+	}
+	for i, test := range tests {
+		if _, err := client.Notifications.New(test.notification, ""); err != test.want {
+			t.Errorf("New() #%d error = %v, want %v", i, err, test.want)
+		}
+		now = now.Add(time.Minute)
+	}
+	if sent != 3 {
+		t.Errorf("sent %d notifications, want 3", sent)
+	}
+}
+
+func TestFileDedupStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup.json")
+	store, err := NewFileDedupStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := DedupRecord{Id: "n1", Recipients: 3, SentAt: time.Date(2015, 9, 24, 12, 0, 0, 0, time.UTC)}
+	if err := store.Put("hash", record); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewFileDedupStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok, _ := reloaded.Get("hash"); !ok || got.Id != "n1" || !got.SentAt.Equal(record.SentAt) {
+		t.Errorf("Reloaded record = %+v, %v, want %+v", got, ok, record)
+	}
+}
@@ -0,0 +1,133 @@
+package gamethrive
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DeviceType is encoded as a number in json, and by name as text.
+type DeviceType int
+
+const (
+	IOS          DeviceType = 0
+	Android      DeviceType = 1
+	Amazon       DeviceType = 2
+	WindowsPhone DeviceType = 3
+	ChromeApp    DeviceType = 4
+	ChromeWeb    DeviceType = 5
+	Safari       DeviceType = 7
+	Firefox      DeviceType = 8
+	MacOS        DeviceType = 9
+	Email        DeviceType = 11
+)
+
+var deviceTypeNames = map[DeviceType]string{
+	IOS:          "ios",
+	Android:      "android",
+	Amazon:       "amazon",
+	WindowsPhone: "windowsphone",
+	ChromeApp:    "chromeapp",
+	ChromeWeb:    "chrome",
+	Safari:       "safari",
+	Firefox:      "firefox",
+	MacOS:        "macos",
+	Email:        "email",
+}
+
+// ParseDeviceType returns the device type for a name as returned by
+// String, failing for unknown names.
+func ParseDeviceType(str string) (DeviceType, error) {
+	str = strings.ToLower(strings.TrimSpace(str))
+	for t, name := range deviceTypeNames {
+		if name == str {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown device type %q", str)
+}
+
+func (t DeviceType) String() string {
+	if name, ok := deviceTypeNames[t]; ok {
+		return name
+	}
+	return "DeviceType(" + strconv.Itoa(int(t)) + ")"
+}
+
+func (t DeviceType) MarshalText() ([]byte, error) {
+	name, ok := deviceTypeNames[t]
+	if !ok {
+		return nil, fmt.Errorf("Unknown device type %d", int(t))
+	}
+	return []byte(name), nil
+}
+
+func (t *DeviceType) UnmarshalText(text []byte) error {
+	dt, err := ParseDeviceType(string(text))
+	if err != nil {
+		return err
+	}
+	*t = dt
+	return nil
+}
+
+// MarshalJSON keeps the numeric wire format, which would otherwise be
+// replaced by MarshalText.
+func (t DeviceType) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Itoa(int(t))), nil
+}
+
+// UnmarshalJSON accepts both numbers and names.
+func (t *DeviceType) UnmarshalJSON(data []byte) error {
+	var n int
+	if err := json.Unmarshal(data, &n); err == nil {
+		*t = DeviceType(n)
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("Invalid device type %s", string(data))
+	}
+	return t.UnmarshalText([]byte(name))
+}
+
+// ValidateIdentifier checks that identifier has the format of a push token
+// (or address) for the device type.
+func (t DeviceType) ValidateIdentifier(identifier string) error {
+	if len(identifier) <= 0 {
+		return fmt.Errorf("Identifier is required for %s devices", t)
+	}
+	switch t {
+	case IOS, Safari, MacOS:
+		if b, err := hex.DecodeString(identifier); err != nil || len(b) != 32 {
+			return fmt.Errorf("Invalid %s identifier: expected a 64 hex digits token", t)
+		}
+	case Android, Amazon, ChromeApp:
+		for _, r := range identifier {
+			if !isTokenRune(r) {
+				return fmt.Errorf("Invalid %s identifier: unexpected character %q", t, r)
+			}
+		}
+	case WindowsPhone, ChromeWeb, Firefox:
+		u, err := url.Parse(identifier)
+		if err != nil || u.Scheme != "https" || len(u.Host) <= 0 {
+			return fmt.Errorf("Invalid %s identifier: expected an https endpoint url", t)
+		}
+	case Email:
+		if _, err := mail.ParseAddress(identifier); err != nil {
+			return fmt.Errorf("Invalid email identifier: %s", err.Error())
+		}
+	default:
+		return fmt.Errorf("Unknown device type %d", int(t))
+	}
+	return nil
+}
+
+func isTokenRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+		r == '-' || r == '_' || r == ':' || r == '.'
+}
@@ -0,0 +1,60 @@
+package gamethrive
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDeviceType_JSON(t *testing.T) {
+	data, err := json.Marshal(Player{DeviceType: Firefox, AppId: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"device_type":8`) {
+		t.Errorf("Marshal = %s, want numeric device_type", data)
+	}
+	var p Player
+	if err := json.Unmarshal([]byte(`{"device_type":"safari"}`), &p); err != nil || p.DeviceType != Safari {
+		t.Errorf("Unmarshal name = %v, %v, want %v", p.DeviceType, err, Safari)
+	}
+	if err := json.Unmarshal([]byte(`{"device_type":11}`), &p); err != nil || p.DeviceType != Email {
+		t.Errorf("Unmarshal number = %v, %v, want %v", p.DeviceType, err, Email)
+	}
+}
+
+func TestParseDeviceType(t *testing.T) {
+	for typ, name := range deviceTypeNames {
+		if got, err := ParseDeviceType(name); err != nil || got != typ {
+			t.Errorf("ParseDeviceType(%q) = %v, %v, want %v", name, got, err, typ)
+		}
+	}
+	if _, err := ParseDeviceType("blackberry"); err == nil {
+		t.Error("ParseDeviceType(blackberry) expected error")
+	}
+}
+
+func TestDeviceType_ValidateIdentifier(t *testing.T) {
+	token := strings.Repeat("ab", 32)
+	tests := []struct {
+		typ   DeviceType
+		id    string
+		valid bool
+	}{
+		{IOS, token, true},
+		{IOS, "abc", false},
+		{Android, "APA91bH-x_y:z", true},
+		{Android, "not a token", false},
+		{ChromeWeb, "https://android.googleapis.com/gcm/send/abc", true},
+		{Firefox, "http://updates.push.services.mozilla.com/abc", false},
+		{Email, "player@example.com", true},
+		{Email, "player", false},
+		{DeviceType(42), token, false},
+	}
+	for _, test := range tests {
+		err := test.typ.ValidateIdentifier(test.id)
+		if (err == nil) != test.valid {
+			t.Errorf("%v.ValidateIdentifier(%q) error = %v, want valid %v", test.typ, test.id, err, test.valid)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package gamethrive
+
+import (
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// Variant is one of the contents compared by an experiment.
+type Variant struct {
+	Name     string            `json:"name"`
+	Contents map[string]string `json:"contents"`
+
+	// NotificationIds holds a notification for each batch of the players
+	// of the variant sent so far.
+	NotificationIds []string  `json:"notification_ids,omitempty"`
+	Stats           OpenStats `json:"stats"`
+}
+
+// Experiment sends variants of a notification to a sample of its audience
+// and, after Deadline, sends the variant with the best open rate to the
+// rest of the audience.
+type Experiment struct {
+	Name string `json:"name"`
+	// Notification is the base notification; its target is split between
+	// the variants and the holdout.
+	Notification Notification `json:"notification"`
+	Variants     []*Variant   `json:"variants"`
+	// SampleRate is the fraction of the audience taking part in the test.
+	SampleRate float64   `json:"sample_rate"`
+	Deadline   time.Time `json:"deadline"`
+	Auth       string    `json:"auth,omitempty"`
+
+	Holdout []string `json:"holdout,omitempty"`
+	Started bool     `json:"started,omitempty"`
+	Winner  string   `json:"winner,omitempty"`
+	// WinnerIds holds the notifications sent to the holdout batches.
+	WinnerIds []string `json:"winner_ids,omitempty"`
+
+	c  *Client
+	mu sync.Mutex
+}
+
+var ErrExperimentStarted = errors.New("Experiment already started")
+
+// NewExperiment creates an experiment over notification, with a 10% sample
+// rate.
+func NewExperiment(client *Client, name string, notification Notification, variants ...*Variant) *Experiment {
+	return &Experiment{
+		Name:         name,
+		Notification: notification,
+		Variants:     variants,
+		SampleRate:   0.1,
+		c:            client,
+	}
+}
+
+// SetClient sets the client of an experiment decoded from json.
+func (e *Experiment) SetClient(client *Client) {
+	e.c = client
+}
+
+// Split assigns each player id to a variant or to the holdout. The split
+// only depends on the experiment name and the id, so it is stable between
+// runs.
+func (e *Experiment) Split(playerIds []string) (groups [][]string, holdout []string) {
+	groups = make([][]string, len(e.Variants))
+	for _, id := range playerIds {
+		h := fnv.New64a()
+		h.Write([]byte(e.Name + ":" + id))
+		sum := h.Sum64()
+		if len(e.Variants) <= 0 || float64(sum%10000) >= e.SampleRate*10000 {
+			holdout = append(holdout, id)
+			continue
+		}
+		i := (sum / 10000) % uint64(len(e.Variants))
+		groups[i] = append(groups[i], id)
+	}
+	return groups, holdout
+}
+
+// Start resolves the audience of the notification, sends every variant to
+// its group and keeps the holdout for Finish. The whole target, including
+// excluded segments and device tokens, is resolved locally from the
+// application players. Groups are sent in batches of player ids the API
+// accepts; batches sent by a Start that failed later are not sent again
+// when it is retried.
+func (e *Experiment) Start() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.Started {
+		return ErrExperimentStarted
+	}
+	if len(e.Variants) <= 0 {
+		return errors.New("Experiment has no variants")
+	}
+	ids, err := e.audience()
+	if err != nil {
+		return err
+	}
+	groups, holdout := e.Split(ids)
+	for i, v := range e.Variants {
+		recipients, err := e.send(groups[i], v.Contents, &v.NotificationIds)
+		v.Stats.Sent += recipients
+		if err != nil {
+			return err
+		}
+	}
+	e.Holdout = holdout
+	e.Started = true
+	return nil
+}
+
+// Open reports an open with Notifications.TrackOpen and records it.
+func (e *Experiment) Open(event *OpenEvent) error {
+	if err := e.c.Notifications.TrackOpen(event); err != nil {
+		return err
+	}
+	e.RecordOpen(event)
+	return nil
+}
+
+// RecordOpen adds an open, reported elsewhere, to its variant.
+func (e *Experiment) RecordOpen(event *OpenEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, v := range e.Variants {
+		for _, id := range v.NotificationIds {
+			if id == event.NotificationId {
+				v.Stats.add(event)
+				return
+			}
+		}
+	}
+}
+
+// Leader returns the variant with the best open rate so far.
+func (e *Experiment) Leader() *Variant {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader()
+}
+
+func (e *Experiment) leader() *Variant {
+	var best *Variant
+	for _, v := range e.Variants {
+		if best == nil || v.Stats.OpenRate() > best.Stats.OpenRate() {
+			best = v
+		}
+	}
+	return best
+}
+
+// Finish sends the leading variant to the holdout once now is past the
+// deadline. It reports whether the winner was sent.
+func (e *Experiment) Finish(now time.Time) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.Started || len(e.Winner) > 0 || now.Before(e.Deadline) {
+		return false, nil
+	}
+	winner := e.leader()
+	if _, err := e.send(e.Holdout, winner.Contents, &e.WinnerIds); err != nil {
+		return false, err
+	}
+	e.Winner = winner.Name
+	return true, nil
+}
+
+// Run waits for the deadline and sends the winner, unless stop is closed
+// first.
+func (e *Experiment) Run(stop <-chan struct{}) error {
+	timer := time.NewTimer(e.Deadline.Sub(time.Now()))
+	defer timer.Stop()
+	select {
+	case <-stop:
+		return nil
+	case now := <-timer.C:
+		_, err := e.Finish(now)
+		return err
+	}
+}
+
+// send delivers contents to playerIds in batches the API accepts, skipping
+// the batches already in sent and adding the new notifications to it.
+func (e *Experiment) send(playerIds []string, contents map[string]string, sent *[]string) (int, error) {
+	recipients := 0
+	batches := playerIdBatches(playerIds)
+	for i := len(*sent); i < len(batches); i++ {
+		n := e.target(batches[i])
+		n.Contents = contents
+		r, err := e.c.Notifications.New(&n, e.Auth)
+		if err != nil && err != ErrDuplicateNotification {
+			return recipients, err
+		}
+		recipients += r
+		*sent = append(*sent, n.Id)
+	}
+	return recipients, nil
+}
+
+func (e *Experiment) target(playerIds []string) Notification {
+	return e.Notification.forPlayers(playerIds)
+}
+
+// audience returns the player ids targeted by the notification. Only
+// targets other than a plain list of player ids need the players of the
+// application.
+func (e *Experiment) audience() ([]string, error) {
+	n := &e.Notification
+	if !n.targetsOthers() && !n.IsIOS && !n.IsAndroid {
+		return n.IncludedPlayerIds, nil
+	}
+	segments, err := e.c.Segments.List(n.AppId, e.Auth)
+	if err != nil {
+		return nil, err
+	}
+	players, err := e.c.Players.All(n.AppId, e.Auth)
+	if err != nil {
+		return nil, err
+	}
+	audience, err := NewSegmentEngine(segments).Evaluate(n, players)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(audience.Players))
+	for i, p := range audience.Players {
+		ids[i] = p.Id
+	}
+	return ids, nil
+}
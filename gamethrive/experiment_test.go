@@ -0,0 +1,181 @@
+package gamethrive
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestExperiment(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	var sent []Notification
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		var n Notification
+		json.NewDecoder(r.Body).Decode(&n)
+		sent = append(sent, n)
+		fmt.Fprintf(w, `{"id":"n%d","recipients":%d}`, len(sent), len(n.IncludedPlayerIds))
+	})
+	mux.HandleFunc("/notifications/n1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":true}`))
+	})
+
+	var ids []string
+	for i := 0; i < 1000; i++ {
+		ids = append(ids, fmt.Sprintf("player-%d", i))
+	}
+	mux.HandleFunc("/players", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Players listed for an experiment targeting player ids")
+	})
+	deadline := time.Date(2015, 9, 24, 12, 0, 0, 0, time.UTC)
+	e := NewExperiment(client, "welcome", Notification{AppId: "app", IncludedPlayerIds: ids},
+		&Variant{Name: "a", Contents: map[string]string{"en": "Hi"}},
+		&Variant{Name: "b", Contents: map[string]string{"en": "Hello"}})
+	e.Deadline = deadline
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 2 || len(e.Holdout) < 850 || len(e.Holdout) > 950 {
+		t.Fatalf("sent %d variants, holdout of %d players", len(sent), len(e.Holdout))
+	}
+	groups, _ := e.Split(ids)
+	if len(groups[0]) != len(sent[0].IncludedPlayerIds) {
+		t.Errorf("Split is not deterministic")
+	}
+	if err := e.Open(&OpenEvent{NotificationId: "n1", Opened: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := e.Finish(deadline.Add(-time.Minute)); ok {
+		t.Error("Finish before the deadline sent the winner")
+	}
+	if ok, err := e.Finish(deadline); !ok || err != nil {
+		t.Fatalf("Finish() = %v, %v", ok, err)
+	}
+	last := sent[len(sent)-1]
+	if e.Winner != "a" || last.Contents["en"] != "Hi" || len(last.IncludedPlayerIds) != len(e.Holdout) {
+		t.Errorf("Winner = %q, sent %v to %d players", e.Winner, last.Contents, len(last.IncludedPlayerIds))
+	}
+}
+
+func TestExperiment_batches(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	var sent []Notification
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		var n Notification
+		json.NewDecoder(r.Body).Decode(&n)
+		sent = append(sent, n)
+		fmt.Fprintf(w, `{"id":"n%d","recipients":%d}`, len(sent), len(n.IncludedPlayerIds))
+	})
+	var players []Player
+	for i := 0; i < 9000; i++ {
+		players = append(players, Player{Id: fmt.Sprintf("player-%d", i)})
+	}
+	servePlayers(mux, players, nil)
+	e := NewExperiment(client, "batches", Notification{AppId: "app", IncludedSegments: []string{"All"}},
+		&Variant{Name: "a", Contents: map[string]string{"en": "Hi"}},
+		&Variant{Name: "b", Contents: map[string]string{"en": "Hello"}})
+	e.SampleRate = 0.5
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Finish(e.Deadline); err != nil {
+		t.Fatal(err)
+	}
+	targeted := map[string]bool{}
+	for _, n := range sent {
+		if len(n.IncludedPlayerIds) > playerIdsPerRequest {
+			t.Errorf("Notification targets %d players, over the limit of %d", len(n.IncludedPlayerIds), playerIdsPerRequest)
+		}
+		for _, id := range n.IncludedPlayerIds {
+			targeted[id] = true
+		}
+	}
+	if len(targeted) != len(players) {
+		t.Errorf("Targeted %d players, want %d", len(targeted), len(players))
+	}
+	a, b := e.Variants[0], e.Variants[1]
+	if len(a.NotificationIds) != 2 || len(b.NotificationIds) != 2 || len(e.WinnerIds) != 3 {
+		t.Errorf("Sent %d, %d and %d batches, want 2, 2 and 3", len(a.NotificationIds), len(b.NotificationIds), len(e.WinnerIds))
+	}
+	if a.Stats.Sent+b.Stats.Sent+len(e.Holdout) != len(players) {
+		t.Errorf("Variants sent to %d players and %d held out", a.Stats.Sent+b.Stats.Sent, len(e.Holdout))
+	}
+}
+
+// servePlayers answers Players.List with players, in a single page, and
+// Segments.List with segments.
+func servePlayers(mux *http.ServeMux, players []Player, segments []Segment) {
+	mux.HandleFunc("/players", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(PlayerList{TotalCount: len(players), Limit: len(players), Players: players})
+	})
+	mux.HandleFunc("/apps/app/segments", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string][]Segment{"segments": segments})
+	})
+}
+
+func TestExperiment_Start_target(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	var sent []Notification
+	fail := true
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		var n Notification
+		json.NewDecoder(r.Body).Decode(&n)
+		if len(sent) == 1 && fail {
+			fail = false
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		sent = append(sent, n)
+		fmt.Fprintf(w, `{"id":"n%d","recipients":%d}`, len(sent), len(n.IncludedPlayerIds))
+	})
+	var players []Player
+	for i := 0; i < 200; i++ {
+		p := Player{Id: fmt.Sprintf("player-%d", i), DeviceType: Android, Identifier: fmt.Sprintf("reg-%d", i)}
+		if i%2 == 0 {
+			p.Tags = map[string]string{"banned": "1"}
+		}
+		players = append(players, p)
+	}
+	servePlayers(mux, players, []Segment{{Name: "Banned", Filters: []Filter{TagFilter("banned", Equal, "1")}}})
+
+	var tokens []string
+	for i := 0; i < 100; i++ {
+		tokens = append(tokens, fmt.Sprintf("reg-%d", i))
+	}
+	n := Notification{AppId: "app", IncludedAndroidRegIds: tokens, ExcludedSegments: []string{"Banned"}}
+	e := NewExperiment(client, "target", n,
+		&Variant{Name: "a", Contents: map[string]string{"en": "Hi"}},
+		&Variant{Name: "b", Contents: map[string]string{"en": "Hello"}})
+	e.SampleRate = 1
+	if err := e.Start(); err == nil {
+		t.Fatal("Start expected error when a variant fails")
+	}
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 2 || sent[0].Contents["en"] == sent[1].Contents["en"] {
+		t.Fatalf("Sent %d notifications, want each variant once", len(sent))
+	}
+	total := 0
+	for _, n := range sent {
+		if len(n.IncludedAndroidRegIds)+len(n.ExcludedSegments) > 0 {
+			t.Errorf("Variant notification keeps the original target: %+v", n)
+		}
+		for _, id := range n.IncludedPlayerIds {
+			var i int
+			fmt.Sscanf(id, "player-%d", &i)
+			if i%2 == 0 || i >= 100 {
+				t.Errorf("Variant sent to %s, which is excluded or not targeted", id)
+			}
+		}
+		total += len(n.IncludedPlayerIds)
+	}
+	if total != 50 {
+		t.Errorf("Variants sent to %d players, want 50", total)
+	}
+}
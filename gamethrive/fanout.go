@@ -0,0 +1,90 @@
+package gamethrive
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrAppSpecificTarget is returned by FanOut for notifications targeting
+// player ids, device tokens or external user ids, which only exist in one
+// application.
+var ErrAppSpecificTarget = errors.New("Player ids, device tokens and external user ids can not be sent to several applications")
+
+// FanOutResult is the outcome of sending a notification to one
+// application.
+// Duplicate is set when the client DedupGuard found the notification
+// already sent, in which case Id and Recipients are those of the original.
+type FanOutResult struct {
+	Id         string
+	Recipients int
+	Duplicate  bool
+	Err        error
+}
+
+// FanOutResults maps application ids to their results.
+type FanOutResults map[string]FanOutResult
+
+// Recipients returns the recipients of all applications.
+func (r FanOutResults) Recipients() int {
+	total := 0
+	for _, result := range r {
+		total += result.Recipients
+	}
+	return total
+}
+
+// Err returns an error describing every failed application, or nil.
+func (r FanOutResults) Err() error {
+	var failed []string
+	for appId, result := range r {
+		if result.Err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", appId, result.Err.Error()))
+		}
+	}
+	if len(failed) <= 0 {
+		return nil
+	}
+	sort.Strings(failed)
+	return fmt.Errorf("Failed to send to %d applications (%s)", len(failed), strings.Join(failed, "; "))
+}
+
+// FanOut sends a copy of notification to every application in apps, which
+// maps application ids to their "API Auth Key". Sends run concurrently and
+// the AppId and Id of notification are ignored. Only segments and filters
+// are meaningful in every application: notifications targeting player ids,
+// device tokens or external user ids fail for every application with
+// ErrAppSpecificTarget.
+func (s *NotificationsService) FanOut(notification *Notification, apps map[string]string) FanOutResults {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := FanOutResults{}
+	if len(notification.IncludedPlayerIds) > 0 || len(notification.IncludedIOSTokens) > 0 ||
+		len(notification.IncludedAndroidRegIds) > 0 || len(notification.IncludedExternalUserIds) > 0 {
+		for appId := range apps {
+			results[appId] = FanOutResult{Err: ErrAppSpecificTarget}
+		}
+		return results
+	}
+	for appId, auth := range apps {
+		wg.Add(1)
+		go func(appId, auth string) {
+			defer wg.Done()
+			n := *notification
+			n.Id = ""
+			n.AppId = appId
+			recipients, err := s.New(&n, auth)
+			duplicate := err == ErrDuplicateNotification
+			if duplicate {
+				err = nil
+			}
+			mu.Lock()
+			results[appId] = FanOutResult{Id: n.Id, Recipients: recipients, Duplicate: duplicate, Err: err}
+			mu.Unlock()
+		}(appId, auth)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,79 @@
+package gamethrive
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNotificationsService_FanOut(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		var n Notification
+		json.NewDecoder(r.Body).Decode(&n)
+		if r.Header.Get("Authorization") != "Basic key-"+n.AppId {
+			t.Errorf("Authorization = %q for app %s", r.Header.Get("Authorization"), n.AppId)
+		}
+		if n.AppId == "broken" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"errors":["Invalid app_id"]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"id":"n-%s","recipients":5}`, n.AppId)
+	})
+	results := client.Notifications.FanOut(&Notification{AppId: "ignored", Contents: map[string]string{"en": "Hi"}}, map[string]string{
+		"ios":    "key-ios",
+		"eu":     "key-eu",
+		"broken": "key-broken",
+	})
+	if len(results) != 3 || results["ios"].Id != "n-ios" || results.Recipients() != 10 {
+		t.Errorf("FanOut() = %+v", results)
+	}
+	if results["broken"].Err == nil || results.Err() == nil {
+		t.Errorf("Expected error for broken app, got %+v", results["broken"])
+	}
+}
+
+func TestNotificationsService_FanOut_duplicate(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	sent := 0
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		var n Notification
+		json.NewDecoder(r.Body).Decode(&n)
+		fmt.Fprintf(w, `{"id":"n-%s","recipients":5}`, n.AppId)
+	})
+	client.Dedup = NewDedupGuard(nil, time.Hour)
+	n := &Notification{Contents: map[string]string{"en": "Hi"}}
+	apps := map[string]string{"ios": "key-ios"}
+	client.Notifications.FanOut(n, apps)
+	results := client.Notifications.FanOut(n, apps)
+	if sent != 1 || results.Err() != nil || !results["ios"].Duplicate || results["ios"].Id != "n-ios" {
+		t.Errorf("Repeated FanOut() = %+v, sent %d", results, sent)
+	}
+}
+
+func TestNotificationsService_FanOut_appSpecific(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Notification with app specific targets was sent")
+	})
+	apps := map[string]string{"ios": "key-ios", "eu": "key-eu"}
+	tests := []Notification{
+		{IncludedPlayerIds: []string{"p1"}},
+		{IncludedIOSTokens: []string{"tok"}},
+		{IncludedAndroidRegIds: []string{"reg"}},
+		{IncludedSegments: []string{"All"}, IncludedExternalUserIds: []string{"u1"}},
+	}
+	for _, n := range tests {
+		results := client.Notifications.FanOut(&n, apps)
+		if len(results) != 2 || results["ios"].Err != ErrAppSpecificTarget || results["eu"].Err != ErrAppSpecificTarget {
+			t.Errorf("FanOut(%+v) = %+v, want %v for every app", n, results, ErrAppSpecificTarget)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package gamethrive
+
+// Filter is a targeting condition on a player field or tag. Consecutive
+// filters are ANDed, an Or filter separates alternatives.
+type Filter struct {
+	Field    string `json:"field,omitempty"`
+	Key      string `json:"key,omitempty"`
+	Relation string `json:"relation,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Operator string `json:"operator,omitempty"`
+}
+
+// Player fields that can be used in filters.
+const (
+	FieldLastSession  = "last_session"
+	FieldFirstSession = "first_session"
+	FieldSessionCount = "session_count"
+	FieldSessionTime  = "session_time"
+	FieldAmountSpent  = "amount_spent"
+	FieldLanguage     = "language"
+	FieldAppVersion   = "app_version"
+	FieldTag          = "tag"
+)
+
+// Relations between a field and a value.
+const (
+	Greater  = ">"
+	Less     = "<"
+	Equal    = "="
+	NotEqual = "!="
+	Exists   = "exists"
+	NotExist = "not_exists"
+)
+
+func FieldFilter(field, relation, value string) Filter {
+	return Filter{Field: field, Relation: relation, Value: value}
+}
+
+func TagFilter(key, relation, value string) Filter {
+	return Filter{Field: FieldTag, Key: key, Relation: relation, Value: value}
+}
+
+func Or() Filter {
+	return Filter{Operator: "OR"}
+}
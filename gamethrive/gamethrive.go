@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"path"
@@ -21,9 +22,28 @@ type Client struct {
 	client    *http.Client
 	BaseURL   *url.URL
 	UserAgent string
+	// TagSchema, when set, validates tags sent with Players.UpdateTags.
+	TagSchema TagSchema
+	// Identities, when set, caches player ids by device and external user id.
+	Identities IdentityStore
+	// VerifySegments makes Notifications.New check that the segments it
+	// targets exist.
+	VerifySegments bool
+	// Dedup, when set, makes Notifications.New skip notifications already
+	// sent within its window.
+	Dedup *DedupGuard
+	// Audit, when set, records every mutating call made on behalf of
+	// Operator.
+	Audit    AuditSink
+	Operator string
+	// OnAuditError, when set, is called when a call can not be recorded in
+	// Audit. The call itself is not reported as failed, since it may have
+	// been delivered already.
+	OnAuditError func(err error)
 
 	Players       PlayersService
 	Notifications NotificationsService
+	Segments      SegmentsService
 }
 
 func NewClient(httpClient *http.Client) *Client {
@@ -37,6 +57,7 @@ func NewClient(httpClient *http.Client) *Client {
 	}
 	client.Players = PlayersService{&client}
 	client.Notifications = NotificationsService{&client}
+	client.Segments = SegmentsService{&client}
 	return &client
 }
 
@@ -63,6 +84,9 @@ func (c *Client) NewRequest(method, urlStr string, body interface{}) (*http.Requ
 }
 
 func (c *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
+	if c.Audit != nil && req.Method != "GET" {
+		return c.doAudited(req, v)
+	}
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, err
@@ -82,6 +106,40 @@ func (c *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
 	return resp, err
 }
 
+// doAudited is Do for calls recorded in the AuditSink. Errors recording
+// the call go to OnAuditError and never change its result, so callers do
+// not retry calls that were delivered.
+func (c *Client) doAudited(req *http.Request, v interface{}) (*http.Response, error) {
+	var body []byte
+	if req.GetBody != nil {
+		if r, err := req.GetBody(); err == nil {
+			body, _ = ioutil.ReadAll(r)
+		}
+	}
+	var response []byte
+	status := 0
+	resp, err := c.client.Do(req)
+	if err == nil {
+		status = resp.StatusCode
+		defer resp.Body.Close()
+		err = checkResponse(resp)
+		if err == nil {
+			response, err = ioutil.ReadAll(resp.Body)
+			if err == nil && v != nil {
+				if w, ok := v.(io.Writer); ok {
+					_, err = w.Write(response)
+				} else {
+					err = json.Unmarshal(response, v)
+				}
+			}
+		}
+	}
+	if auditErr := c.audit(req.Method, req.URL.Path, body, status, response, err); auditErr != nil && c.OnAuditError != nil {
+		c.OnAuditError(auditErr)
+	}
+	return resp, err
+}
+
 type ErrorResponse struct {
 	*http.Response
 	Errors []string `json: "errors"`
@@ -0,0 +1,158 @@
+package gamethrive
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// IdentityStore maps devices and external user ids to GameThrive player
+// ids. When Client.Identities is set, Players.New reuses the id of devices
+// already registered and records external user ids.
+type IdentityStore interface {
+	// PlayerId returns the player id registered for a device key.
+	PlayerId(deviceKey string) (string, bool, error)
+	SetPlayerId(deviceKey, playerId string) error
+	// PlayerIds returns the players (devices) of an external user id.
+	PlayerIds(externalUserId string) ([]string, error)
+	AddExternalUserId(externalUserId, playerId string) error
+}
+
+// DeviceKey identifies the device of a player within its application, by
+// push identifier or, lacking one, by advertising id. It is empty if the
+// player has neither.
+func DeviceKey(player *Player) string {
+	if keys := deviceKeys(player); len(keys) > 0 {
+		return keys[0]
+	}
+	return ""
+}
+
+// deviceKeys returns every key the device of a player can be found by.
+func deviceKeys(player *Player) []string {
+	var keys []string
+	if len(player.Identifier) > 0 {
+		keys = append(keys, fmt.Sprintf("%s/%d/id:%s", player.AppId, int(player.DeviceType), player.Identifier))
+	}
+	if len(player.AdvertisingId) > 0 {
+		keys = append(keys, fmt.Sprintf("%s/%d/ad:%s", player.AppId, int(player.DeviceType), player.AdvertisingId))
+	}
+	return keys
+}
+
+// lookupDevice returns the player id stored for any of the device keys of
+// player.
+func lookupDevice(store IdentityStore, player *Player) (string, bool, error) {
+	for _, key := range deviceKeys(player) {
+		id, ok, err := store.PlayerId(key)
+		if err != nil || ok {
+			return id, ok, err
+		}
+	}
+	return "", false, nil
+}
+
+// ResolveExternalUserIds adds the players of every external user id
+// targeted by the notification to IncludedPlayerIds, clearing
+// IncludedExternalUserIds.
+func (n *Notification) ResolveExternalUserIds(store IdentityStore) error {
+	seen := map[string]bool{}
+	for _, id := range n.IncludedPlayerIds {
+		seen[id] = true
+	}
+	for _, ext := range n.IncludedExternalUserIds {
+		ids, err := store.PlayerIds(ext)
+		if err != nil {
+			return err
+		}
+		if len(ids) <= 0 {
+			return fmt.Errorf("No players for external user id %q", ext)
+		}
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				n.IncludedPlayerIds = append(n.IncludedPlayerIds, id)
+			}
+		}
+	}
+	n.IncludedExternalUserIds = nil
+	return nil
+}
+
+type identities struct {
+	Devices map[string]string   `json:"devices"`
+	Users   map[string][]string `json:"users"`
+}
+
+// MemoryIdentityStore maps device keys and external user ids to player ids
+// in a process. Stores opened with NewFileIdentityStore also keep the
+// mappings on disk, so later runs reuse the players already registered.
+type MemoryIdentityStore struct {
+	path string
+	mu   sync.Mutex
+	ids  identities
+}
+
+func NewMemoryIdentityStore() *MemoryIdentityStore {
+	return &MemoryIdentityStore{ids: identities{
+		Devices: map[string]string{},
+		Users:   map[string][]string{},
+	}}
+}
+
+// NewFileIdentityStore opens the identities recorded at path, rewriting the
+// file whenever a player id or external user id is added. A missing file
+// starts an empty store.
+func NewFileIdentityStore(path string) (*MemoryIdentityStore, error) {
+	s := NewMemoryIdentityStore()
+	s.path = path
+	if err := loadJSONFile(path, "identity store", &s.ids); err != nil {
+		return nil, err
+	}
+	if s.ids.Devices == nil {
+		s.ids.Devices = map[string]string{}
+	}
+	if s.ids.Users == nil {
+		s.ids.Users = map[string][]string{}
+	}
+	return s, nil
+}
+
+func (s *MemoryIdentityStore) PlayerId(deviceKey string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.ids.Devices[deviceKey]
+	return id, ok, nil
+}
+
+func (s *MemoryIdentityStore) SetPlayerId(deviceKey, playerId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids.Devices[deviceKey] = playerId
+	return s.save()
+}
+
+func (s *MemoryIdentityStore) PlayerIds(externalUserId string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ids.Users[externalUserId]...), nil
+}
+
+func (s *MemoryIdentityStore) AddExternalUserId(externalUserId, playerId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := s.ids.Users[externalUserId]
+	i := sort.SearchStrings(ids, playerId)
+	if i < len(ids) && ids[i] == playerId {
+		return nil
+	}
+	ids = append(ids, "")
+	copy(ids[i+1:], ids[i:])
+	ids[i] = playerId
+	s.ids.Users[externalUserId] = ids
+	return s.save()
+}
+
+func (s *MemoryIdentityStore) save() error {
+	return saveJSONFile(s.path, s.ids)
+}
@@ -0,0 +1,90 @@
+package gamethrive
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDeviceKey(t *testing.T) {
+	tests := []struct {
+		player Player
+		want   string
+	}{
+		{Player{AppId: "app", DeviceType: Android, Identifier: "reg", AdvertisingId: "ad"}, "app/1/id:reg"},
+		{Player{AppId: "app", DeviceType: IOS, AdvertisingId: "ad"}, "app/0/ad:ad"},
+		{Player{AppId: "app"}, ""},
+	}
+	for _, test := range tests {
+		if got := DeviceKey(&test.player); got != test.want {
+			t.Errorf("DeviceKey(%+v) = %q, want %q", test.player, got, test.want)
+		}
+	}
+}
+
+func TestFileIdentityStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "identities.json")
+	store, err := NewFileIdentityStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.SetPlayerId("app/1/id:reg", "p1")
+	store.AddExternalUserId("u1", "p2")
+	store.AddExternalUserId("u1", "p1")
+	store.AddExternalUserId("u1", "p2")
+
+	reloaded, err := NewFileIdentityStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok, err := reloaded.PlayerId("app/1/id:reg"); id != "p1" || !ok || err != nil {
+		t.Errorf("PlayerId = %q, %v, %v, want p1", id, ok, err)
+	}
+	if ids, _ := reloaded.PlayerIds("u1"); !reflect.DeepEqual(ids, []string{"p1", "p2"}) {
+		t.Errorf("PlayerIds = %v, want [p1 p2]", ids)
+	}
+}
+
+func TestNotification_ResolveExternalUserIds(t *testing.T) {
+	store := NewMemoryIdentityStore()
+	store.AddExternalUserId("u1", "p1")
+	store.AddExternalUserId("u1", "p2")
+	store.AddExternalUserId("u2", "p3")
+	n := &Notification{IncludedPlayerIds: []string{"p2"}, IncludedExternalUserIds: []string{"u1", "u2"}}
+	if err := n.ResolveExternalUserIds(store); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"p2", "p1", "p3"}; !reflect.DeepEqual(n.IncludedPlayerIds, want) || n.IncludedExternalUserIds != nil {
+		t.Errorf("Resolved notification targets %v and %v, want %v", n.IncludedPlayerIds, n.IncludedExternalUserIds, want)
+	}
+	n = &Notification{IncludedExternalUserIds: []string{"unknown"}}
+	if err := n.ResolveExternalUserIds(store); err == nil {
+		t.Error("ResolveExternalUserIds of an unknown user expected error")
+	}
+}
+
+func TestNotificationsNew_externalUserIds(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if _, ok := body["include_external_user_ids"]; ok {
+			t.Errorf("Request body %v has unresolved external user ids", body)
+		}
+		if ids := fmt.Sprint(body["include_player_ids"]); ids != "[p1]" {
+			t.Errorf("include_player_ids = %s, want [p1]", ids)
+		}
+		fmt.Fprint(w, `{"id":"n1","recipients":1}`)
+	})
+	store := NewMemoryIdentityStore()
+	store.AddExternalUserId("u1", "p1")
+	client.Identities = store
+	n := &Notification{AppId: "app", IncludedExternalUserIds: []string{"u1"}}
+	if _, err := client.Notifications.New(n, ""); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,191 @@
+package gamethrive
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Payload size limits, in bytes, of the push services.
+const (
+	IOSPayloadLimit     = 2048
+	AndroidPayloadLimit = 4096
+)
+
+type LintSeverity string
+
+const (
+	LintError   LintSeverity = "error"
+	LintWarning LintSeverity = "warning"
+)
+
+// LintIssue is a problem found in a notification. Platform and Language are
+// empty when the issue is not specific to them.
+type LintIssue struct {
+	Severity LintSeverity
+	Platform string
+	Language string
+	Message  string
+}
+
+func (i LintIssue) String() string {
+	where := ""
+	if len(i.Platform) > 0 {
+		where += " " + i.Platform
+	}
+	if len(i.Language) > 0 {
+		where += " [" + i.Language + "]"
+	}
+	return fmt.Sprintf("%s%s: %s", i.Severity, where, i.Message)
+}
+
+// PayloadSize is the approximate size of the payload delivered to a device
+// of a platform with the contents of a language.
+type PayloadSize struct {
+	Platform string
+	Language string
+	Size     int
+	Limit    int
+}
+
+type LintReport struct {
+	Sizes  []PayloadSize
+	Issues []LintIssue
+}
+
+func (r *LintReport) HasErrors() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == LintError {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *LintReport) add(severity LintSeverity, platform, lang, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, LintIssue{severity, platform, lang, fmt.Sprintf(format, args...)})
+}
+
+// LintNotification checks offline that notification fits in the payload of
+// every targeted platform, in every language, and that its texts do not
+// have characters devices cannot show. Sizes are approximations of what
+// the push services receive: each device gets the contents of one language
+// along with its heading, buttons, data, url, sound and badge.
+func LintNotification(notification *Notification) *LintReport {
+	report := new(LintReport)
+	if len(notification.Contents["en"]) <= 0 {
+		report.add(LintError, "", "en", "english contents are required")
+	}
+	langs := make([]string, 0, len(notification.Contents))
+	for lang := range notification.Contents {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	platforms := []string{}
+	if notification.IsIOS || !notification.IsAndroid {
+		platforms = append(platforms, "ios")
+	}
+	if notification.IsAndroid || !notification.IsIOS {
+		platforms = append(platforms, "android")
+	}
+	for _, lang := range langs {
+		text := notification.Contents[lang]
+		if len(text) <= 0 {
+			report.add(LintWarning, "", lang, "contents are empty")
+		}
+		lintText(report, lang, "contents", text)
+		lintText(report, lang, "heading", notification.Headings[lang])
+		for _, platform := range platforms {
+			size, limit := payloadSize(notification, platform, lang, text)
+			report.Sizes = append(report.Sizes, PayloadSize{platform, lang, size, limit})
+			if size > limit {
+				report.add(LintError, platform, lang, "payload of %d bytes exceeds the limit of %d bytes by %d", size, limit, size-limit)
+			} else if size*10 > limit*9 {
+				report.add(LintWarning, platform, lang, "payload of %d bytes is close to the limit of %d bytes", size, limit)
+			}
+		}
+	}
+	keys := make([]string, 0, len(notification.Data))
+	for key := range notification.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		lintText(report, "", "data "+key, notification.Data[key])
+	}
+	switch notification.IOSBadgeType {
+	case SetTo:
+		if notification.IOSBadgeCount < 0 {
+			report.add(LintError, "ios", "", "badge count cannot be negative when set")
+		}
+	case Increase:
+		if notification.IOSBadgeCount == 0 {
+			report.add(LintWarning, "ios", "", "badge is increased by zero")
+		}
+	}
+	return report
+}
+
+func lintText(report *LintReport, lang, field, text string) {
+	if !utf8.ValidString(text) {
+		report.add(LintError, "", lang, "%s is not valid UTF-8", field)
+		return
+	}
+	for _, r := range text {
+		switch {
+		case r == utf8.RuneError:
+			report.add(LintWarning, "", lang, "%s has a replacement character, it was probably badly encoded", field)
+		case unicode.IsControl(r) && r != '\n' && r != '\t':
+			report.add(LintWarning, "", lang, "%s has the control character %U", field, r)
+		case unicode.In(r, unicode.Co):
+			report.add(LintWarning, "", lang, "%s has the private use character %U, devices cannot show it", field, r)
+		default:
+			continue
+		}
+		return
+	}
+}
+
+// payloadSize approximates the payload GameThrive sends to a platform.
+func payloadSize(n *Notification, platform, lang, text string) (int, int) {
+	custom := map[string]interface{}{"i": "00000000-0000-0000-0000-000000000000"}
+	if len(n.Data) > 0 {
+		custom["a"] = n.Data
+	}
+	if len(n.URL) > 0 {
+		custom["u"] = n.URL
+	}
+	if len(n.Buttons) > 0 {
+		custom["o"] = n.Buttons
+	}
+	var payload interface{}
+	limit := AndroidPayloadLimit
+	if platform == "ios" {
+		limit = IOSPayloadLimit
+		aps := map[string]interface{}{"alert": text}
+		if len(n.IOSSound) > 0 {
+			aps["sound"] = n.IOSSound
+		}
+		if n.IOSBadgeType == SetTo || n.IOSBadgeType == Increase {
+			aps["badge"] = n.IOSBadgeCount
+		}
+		if n.ContentAvailable {
+			aps["content-available"] = 1
+		}
+		payload = map[string]interface{}{"aps": aps, "custom": custom}
+	} else {
+		data := map[string]interface{}{"alert": text, "custom": custom}
+		if heading := n.Headings[lang]; len(heading) > 0 {
+			data["title"] = heading
+		}
+		if len(n.AndroidSound) > 0 {
+			data["sound"] = n.AndroidSound
+		}
+		payload = map[string]interface{}{"data": data}
+	}
+	data, _ := json.Marshal(payload)
+	return len(data), limit
+}
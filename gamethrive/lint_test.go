@@ -0,0 +1,35 @@
+package gamethrive
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLintNotification(t *testing.T) {
+	n := &Notification{
+		IsIOS:     true,
+		IsAndroid: true,
+		Contents: map[string]string{
+			"en": "New levels are available!",
+			"ja": strings.Repeat("新しいレベル", 120),
+			"es": "Nuevos niveles\x01",
+		},
+		Data: map[string]string{"level": "\xff"},
+	}
+	report := LintNotification(n)
+	if len(report.Sizes) != 6 {
+		t.Fatalf("Sizes = %+v", report.Sizes)
+	}
+	want := []string{
+		"warning [es]: contents has the control character U+0001",
+		"error ios [ja]: payload of 2254 bytes exceeds the limit of 2048 bytes by 206",
+		"error: data level is not valid UTF-8",
+	}
+	var got []string
+	for _, issue := range report.Issues {
+		got = append(got, issue.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") || !report.HasErrors() {
+		t.Errorf("Issues =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,192 @@
+package locale
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var errNoLanguage = errors.New("Po file has no Language header")
+
+// Catalog holds translated messages by language code and message id.
+type Catalog map[string]map[string]string
+
+// Add merges the messages of a language into the catalog.
+func (c Catalog) Add(tag string, messages map[string]string) error {
+	lang, err := Normalize(tag)
+	if err != nil {
+		return err
+	}
+	if c[lang] == nil {
+		c[lang] = map[string]string{}
+	}
+	for id, text := range messages {
+		c[lang][id] = text
+	}
+	return nil
+}
+
+// Contents returns the translations of a message, ready to be used as
+// Notification.Contents.
+func (c Catalog) Contents(id string) map[string]string {
+	contents := map[string]string{}
+	for lang, messages := range c {
+		if text, ok := messages[id]; ok && len(text) > 0 {
+			contents[lang] = text
+		}
+	}
+	return contents
+}
+
+// Languages returns the languages of the catalog, sorted.
+func (c Catalog) Languages() []string {
+	langs := make([]string, 0, len(c))
+	for lang := range c {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// ReadJSON reads a json object of languages to objects of message ids to
+// translations, e.g. {"en": {"welcome": "Welcome!"}}.
+func (c Catalog) ReadJSON(r io.Reader) error {
+	var langs map[string]map[string]string
+	if err := json.NewDecoder(r).Decode(&langs); err != nil {
+		return err
+	}
+	for tag, messages := range langs {
+		if err := c.Add(tag, messages); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadPO reads a gettext po file. The language is taken from tag or, when
+// empty, from the Language header of the file. Fuzzy and untranslated
+// entries are skipped, contexts are ignored and plural messages use their
+// first form.
+func (c Catalog) ReadPO(r io.Reader, tag string) error {
+	messages := map[string]string{}
+	var (
+		id, str, keyword string
+		fuzzy, pending   bool
+		header           string
+	)
+	flush := func() {
+		if pending && !fuzzy {
+			if len(id) <= 0 {
+				header = str
+			} else if len(str) > 0 {
+				messages[id] = str
+			}
+		}
+		id, str, keyword = "", "", ""
+		fuzzy, pending = false, false
+	}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) <= 0 {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if pending && strings.HasPrefix(keyword, "msgstr") {
+				flush()
+			}
+			if strings.HasPrefix(line, "#,") && strings.Contains(line, "fuzzy") {
+				fuzzy = true
+			}
+			keyword = "comment"
+			continue
+		}
+		if strings.HasPrefix(line, `"`) {
+			s, err := strconv.Unquote(line)
+			if err != nil {
+				return fmt.Errorf("Invalid po string at line %d", n)
+			}
+			switch keyword {
+			case "msgid":
+				id += s
+			case "msgstr", "msgstr[0]":
+				str += s
+			}
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			return fmt.Errorf("Invalid po line %d", n)
+		}
+		s, err := strconv.Unquote(strings.TrimSpace(fields[1]))
+		if err != nil {
+			return fmt.Errorf("Invalid po string at line %d", n)
+		}
+		switch fields[0] {
+		case "msgctxt", "msgid":
+			if pending && strings.HasPrefix(keyword, "msgstr") {
+				flush()
+			}
+			if fields[0] == "msgid" {
+				id = s
+			}
+			pending = true
+		case "msgstr", "msgstr[0]":
+			str = s
+		case "msgid_plural":
+		default:
+			if !strings.HasPrefix(fields[0], "msgstr[") {
+				return fmt.Errorf("Unknown po keyword %q at line %d", fields[0], n)
+			}
+		}
+		keyword = fields[0]
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	flush()
+	if len(tag) <= 0 {
+		for _, h := range strings.Split(header, "\n") {
+			if strings.HasPrefix(h, "Language:") {
+				tag = strings.TrimSpace(strings.TrimPrefix(h, "Language:"))
+			}
+		}
+		if len(tag) <= 0 {
+			return errNoLanguage
+		}
+	}
+	return c.Add(tag, messages)
+}
+
+// LoadFile reads a catalog file by its extension, ".json" or ".po". The
+// language of po files without a Language header is the file name, as in
+// "es.po".
+func (c Catalog) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	switch ext := filepath.Ext(path); ext {
+	case ".json":
+		return c.ReadJSON(file)
+	case ".po":
+		err := c.ReadPO(file, "")
+		if err == errNoLanguage {
+			if _, err := file.Seek(0, 0); err != nil {
+				return err
+			}
+			return c.ReadPO(file, strings.TrimSuffix(filepath.Base(path), ext))
+		}
+		return err
+	default:
+		return fmt.Errorf("Unknown catalog format %q", ext)
+	}
+}
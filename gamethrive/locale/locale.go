@@ -0,0 +1,134 @@
+// Package locale normalizes the language codes used by GameThrive in
+// Player.Language and Notification.Contents, loads translation catalogs and
+// checks that notifications have content for the languages of their
+// players.
+package locale
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"../../gamethrive"
+)
+
+// Default is the language GameThrive falls back to, and the one every
+// notification must have.
+const Default = "en"
+
+// Languages are the codes supported by GameThrive. They are lower case two
+// letter codes, except for chinese.
+var Languages = []string{
+	"ar", "bg", "ca", "cs", "da", "de", "el", "en", "es", "et", "fa", "fi",
+	"fr", "he", "hi", "hr", "hu", "id", "it", "ja", "ka", "ko", "lt", "lv",
+	"ms", "nb", "nl", "pa", "pl", "pt", "ro", "ru", "sk", "sr", "sv", "th",
+	"tr", "uk", "vi", "zh-Hans", "zh-Hant",
+}
+
+var supported = map[string]bool{}
+
+func init() {
+	for _, code := range Languages {
+		supported[code] = true
+	}
+}
+
+// aliases maps deprecated or alternative codes to supported ones.
+var aliases = map[string]string{
+	"iw": "he",
+	"in": "id",
+	"no": "nb",
+	"nn": "nb",
+}
+
+func IsSupported(code string) bool {
+	return supported[code]
+}
+
+// Normalize converts a BCP-47 tag, like "en-US", "pt_BR" or "zh-Hant-TW",
+// to a supported language code. Chinese is mapped to "zh-Hans" or
+// "zh-Hant" from its script or region, defaulting to simplified.
+func Normalize(tag string) (string, error) {
+	parts := strings.Split(strings.Replace(strings.TrimSpace(tag), "_", "-", -1), "-")
+	lang := strings.ToLower(parts[0])
+	if alias, ok := aliases[lang]; ok {
+		lang = alias
+	}
+	if lang == "zh" {
+		lang = "zh-Hans"
+		for _, sub := range parts[1:] {
+			switch strings.ToLower(sub) {
+			case "hant", "tw", "hk", "mo":
+				lang = "zh-Hant"
+			}
+		}
+	}
+	if !supported[lang] {
+		return "", fmt.Errorf("Unsupported language %q", tag)
+	}
+	return lang, nil
+}
+
+// NormalizeContents returns contents keyed by normalized language codes.
+func NormalizeContents(contents map[string]string) (map[string]string, error) {
+	normalized := make(map[string]string, len(contents))
+	for tag, text := range contents {
+		lang, err := Normalize(tag)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := normalized[lang]; ok {
+			return nil, fmt.Errorf("Language %s is repeated in contents", lang)
+		}
+		normalized[lang] = text
+	}
+	return normalized, nil
+}
+
+// PlayerLanguage returns the normalized language of a player, or Default
+// if it is empty or not supported.
+func PlayerLanguage(player *gamethrive.Player) string {
+	lang, err := Normalize(player.Language)
+	if err != nil {
+		return Default
+	}
+	return lang
+}
+
+// Missing counts, by language, the players that would not get the
+// notification in their language.
+func Missing(notification *gamethrive.Notification, players []gamethrive.Player) (map[string]int, error) {
+	contents, err := NormalizeContents(notification.Contents)
+	if err != nil {
+		return nil, err
+	}
+	missing := map[string]int{}
+	for i := range players {
+		lang := PlayerLanguage(&players[i])
+		if len(contents[lang]) <= 0 {
+			missing[lang]++
+		}
+	}
+	return missing, nil
+}
+
+// Check returns an error when the notification has no content in Default
+// or in the language of any of the players.
+func Check(notification *gamethrive.Notification, players []gamethrive.Player) error {
+	missing, err := Missing(notification, players)
+	if err != nil {
+		return err
+	}
+	langs := make([]string, 0, len(missing)+1)
+	for lang := range missing {
+		langs = append(langs, lang)
+	}
+	if contents, _ := NormalizeContents(notification.Contents); len(contents[Default]) <= 0 && missing[Default] <= 0 {
+		langs = append(langs, Default)
+	}
+	if len(langs) <= 0 {
+		return nil
+	}
+	sort.Strings(langs)
+	return fmt.Errorf("Notification has no content for %s", strings.Join(langs, ", "))
+}
@@ -0,0 +1,72 @@
+package locale
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"../../gamethrive"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"en":         "en",
+		"en-US":      "en",
+		"pt_BR":      "pt",
+		"zh":         "zh-Hans",
+		"zh-CN":      "zh-Hans",
+		"zh-Hant":    "zh-Hant",
+		"zh-TW":      "zh-Hant",
+		"zh-Hant-HK": "zh-Hant",
+		"iw":         "he",
+	}
+	for tag, want := range tests {
+		if got, err := Normalize(tag); got != want || err != nil {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", tag, got, err, want)
+		}
+	}
+	if _, err := Normalize("tlh"); err == nil {
+		t.Error("Expected error for unsupported language")
+	}
+}
+
+func TestCatalog_ReadPO(t *testing.T) {
+	po := `msgid ""
+msgstr ""
+"Language: es-ES\n"
+
+#: game.go:10
+msgid "welcome"
+msgstr "¡Bienvenido!"
+
+#, fuzzy
+msgid "bye"
+msgstr "Adiós"
+
+msgid "long"
+msgstr ""
+"Una línea "
+"larga"
+`
+	c := Catalog{}
+	if err := c.ReadPO(strings.NewReader(po), ""); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"welcome": "¡Bienvenido!", "long": "Una línea larga"}
+	if !reflect.DeepEqual(c["es"], want) {
+		t.Errorf("ReadPO() = %v, want %v", c, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	players := []gamethrive.Player{{Language: "en-GB"}, {Language: "zh_TW"}, {Language: "klingon"}}
+	n := &gamethrive.Notification{Contents: map[string]string{"en": "Hi", "zh-Hans": "你好"}}
+	err := Check(n, players)
+	if err == nil || !strings.HasSuffix(err.Error(), "zh-Hant") {
+		t.Errorf("Check() = %v", err)
+	}
+	n.Contents["zh-TW"] = "你好"
+	if err := Check(n, players); err != nil {
+		t.Errorf("Check() = %v", err)
+	}
+}
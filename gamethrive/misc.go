@@ -1,8 +1,13 @@
 package gamethrive
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 )
 
 func mustParse(u *url.URL, err error) *url.URL {
@@ -11,3 +16,55 @@ func mustParse(u *url.URL, err error) *url.URL {
 	}
 	return u
 }
+
+func setAuth(req *http.Request, auth string) {
+	if len(auth) > 0 {
+		req.Header.Set("Authorization", "Basic "+auth)
+	}
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// loadJSONFile decodes the json file at path into v, leaving v untouched
+// when the file does not exist. kind names the content in errors.
+func loadJSONFile(path, kind string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("Invalid %s %s: %s", kind, path, err.Error())
+	}
+	return nil
+}
+
+// saveJSONFile replaces the file at path with v encoded as json. Nothing is
+// written when path is empty.
+func saveJSONFile(path string, v interface{}) error {
+	if len(path) <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
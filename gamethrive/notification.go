@@ -1,7 +1,9 @@
 package gamethrive
 
 import (
-	"time"
+	"errors"
+	"fmt"
+	"net/url"
 )
 
 type NotificationsService struct {
@@ -15,12 +17,17 @@ type Notification struct {
 	IsIOS     bool              `json:"isIos"`
 	IsAndroid bool              `json:"isAndroid"`
 	Contents  map[string]string `json:"contents"`
+	// Headings are the titles by language, shown on Android.
+	Headings map[string]string `json:"headings,omitempty"`
 	// Target Parameters
 	IncludedSegments      []string `json:"included_segments,omitempty"`
 	ExcludedSegments      []string `json:"excluded_segments,omitempty"`
 	IncludedPlayerIds     []string `json:"include_player_ids,omitempty"`
 	IncludedIOSTokens     []string `json:"include_ios_tokens,omitempty"`
 	IncludedAndroidRegIds []string `json:"include_android_reg_ids,omitempty"`
+	// IncludedExternalUserIds targets players by ExternalUserId.
+	IncludedExternalUserIds []string `json:"include_external_user_ids,omitempty"`
+	Filters                 []Filter `json:"filters,omitempty"`
 	// Optional Body Paramters
 
 	ContentAvailable   bool              `json:"content_available,omitempty"`
@@ -30,10 +37,19 @@ type Notification struct {
 	AndroidSound       string            `json:"android_sound,omitempty"`
 	Data               map[string]string `json:"data,omitempty"`
 	URL                string            `json:"url,omitempty"`
-	SendAfter          *time.Time        `json:"send_after,omitempty"`
+	Buttons            []Button          `json:"buttons,omitempty"`
+	SendAfter          *SendTime         `json:"send_after,omitempty"`
 	SendUserActiveTime bool              `json:"send_at_user_active_time,omitempty"`
 }
 
+// Button is an action button shown with a notification. Its Id is
+// reported as the action id when clicked.
+type Button struct {
+	Id   string `json:"id"`
+	Text string `json:"text"`
+	Icon string `json:"icon,omitempty"`
+}
+
 type BadgeType string
 
 const (
@@ -42,14 +58,72 @@ const (
 	Increase BadgeType = "Increase"
 )
 
+// playerIdsPerRequest is the most player ids a notification may target.
+const playerIdsPerRequest = 2000
+
+// playerIdBatches splits playerIds in batches a notification can target.
+func playerIdBatches(playerIds []string) [][]string {
+	var batches [][]string
+	for len(playerIds) > playerIdsPerRequest {
+		batches = append(batches, playerIds[:playerIdsPerRequest])
+		playerIds = playerIds[playerIdsPerRequest:]
+	}
+	if len(playerIds) > 0 {
+		batches = append(batches, playerIds)
+	}
+	return batches
+}
+
+// targetsOthers reports whether the notification has targets other than
+// IncludedPlayerIds.
+func (n *Notification) targetsOthers() bool {
+	return len(n.IncludedSegments)+len(n.ExcludedSegments)+len(n.Filters)+
+		len(n.IncludedExternalUserIds)+len(n.IncludedIOSTokens)+len(n.IncludedAndroidRegIds) > 0
+}
+
+// forPlayers returns a copy of the notification, without id, targeting
+// only playerIds.
+func (n Notification) forPlayers(playerIds []string) Notification {
+	n.Id = ""
+	n.IncludedSegments = nil
+	n.ExcludedSegments = nil
+	n.Filters = nil
+	n.IncludedExternalUserIds = nil
+	n.IncludedIOSTokens = nil
+	n.IncludedAndroidRegIds = nil
+	n.IncludedPlayerIds = playerIds
+	return n
+}
+
+// New sends a notification. When the client has an IdentityStore, the
+// external user ids targeted are resolved to player ids first. When the
+// client has a DedupGuard and the same notification was already sent, the
+// original id is set and ErrDuplicateNotification is returned.
 func (s *NotificationsService) New(notification *Notification, auth string) (int, error) {
+	if len(notification.IncludedExternalUserIds) > 0 && s.c.Identities != nil {
+		if err := notification.ResolveExternalUserIds(s.c.Identities); err != nil {
+			return 0, err
+		}
+	}
+	if s.c.VerifySegments {
+		if err := s.c.Segments.Check(notification, auth); err != nil {
+			return 0, err
+		}
+	}
+	if s.c.Dedup != nil {
+		return s.c.Dedup.send(notification, func() (int, error) {
+			return s.send(notification, auth)
+		})
+	}
+	return s.send(notification, auth)
+}
+
+func (s *NotificationsService) send(notification *Notification, auth string) (int, error) {
 	req, err := s.c.NewRequest("POST", "notifications", notification)
 	if err != nil {
 		return 0, err
 	}
-	if len(auth) > 0 {
-		req.Header.Set("Authorization", "Basic "+auth)
-	}
+	setAuth(req, auth)
 	var res struct {
 		Id         string `json:"id"`
 		Recipients int    `json:"recipients"`
@@ -63,18 +137,89 @@ func (s *NotificationsService) New(notification *Notification, auth string) (int
 }
 
 func (s *NotificationsService) Open(notification *Notification, opened bool) error {
-	urlStr := "notifications/" + notification.Id
-	body := struct {
-		Opened bool   `json:"opened"`
-		AppId  string `json:"app_id"`
-	}{
-		Opened: opened,
-		AppId:  notification.AppId,
+	return s.TrackOpen(&OpenEvent{
+		NotificationId: notification.Id,
+		AppId:          notification.AppId,
+		Opened:         opened,
+	})
+}
+
+// SentNotification is a notification as listed by the API, with its
+// delivery stats.
+type SentNotification struct {
+	Notification
+	Id string `json:"id"`
+	// ScheduledAt is the unixtime it is or was delivered at.
+	ScheduledAt int  `json:"send_after"`
+	QueuedAt    int  `json:"queued_at"`
+	Successful  int  `json:"successful"`
+	Failed      int  `json:"failed"`
+	Converted   int  `json:"converted"`
+	Remaining   int  `json:"remaining"`
+	Canceled    bool `json:"canceled"`
+}
+
+type NotificationList struct {
+	TotalCount    int                `json:"total_count"`
+	Offset        int                `json:"offset"`
+	Limit         int                `json:"limit"`
+	Notifications []SentNotification `json:"notifications"`
+}
+
+const notificationsPageSize = 50
+
+// List returns a page of the notifications of an application, including
+// scheduled ones. It requires the "API Auth Key" of the application.
+func (s *NotificationsService) List(appId string, auth string, limit, offset int) (*NotificationList, error) {
+	if len(appId) <= 0 {
+		return nil, errors.New("App id is required")
+	}
+	urlStr := fmt.Sprintf("notifications?app_id=%s&limit=%d&offset=%d", url.QueryEscape(appId), limit, offset)
+	req, err := s.c.NewRequest("GET", urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+	setAuth(req, auth)
+	list := new(NotificationList)
+	_, err = s.c.Do(req, list)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list.Notifications {
+		n := &list.Notifications[i]
+		n.Notification.Id = n.Id
+		n.AppId = appId
+	}
+	return list, nil
+}
+
+// All fetches every notification of an application with Notifications.List.
+func (s *NotificationsService) All(appId string, auth string) ([]SentNotification, error) {
+	var notifications []SentNotification
+	for offset := 0; ; {
+		list, err := s.List(appId, auth, notificationsPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, list.Notifications...)
+		offset += len(list.Notifications)
+		if len(list.Notifications) <= 0 || offset >= list.TotalCount {
+			return notifications, nil
+		}
+	}
+}
+
+// Cancel stops a scheduled notification from being delivered.
+func (s *NotificationsService) Cancel(appId, notificationId, auth string) error {
+	if len(notificationId) <= 0 {
+		return errors.New("Notification id is required")
 	}
-	req, err := s.c.NewRequest("PUT", urlStr, body)
+	urlStr := fmt.Sprintf("notifications/%s?app_id=%s", notificationId, url.QueryEscape(appId))
+	req, err := s.c.NewRequest("DELETE", urlStr, nil)
 	if err != nil {
 		return err
 	}
+	setAuth(req, auth)
 	_, err = s.c.Do(req, nil)
 	return err
 }
@@ -0,0 +1,90 @@
+package gamethrive
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestNotificationsService_All(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	requests := 0
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Method != "GET" {
+			t.Errorf("Request method = %v, want GET", r.Method)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Basic key" {
+			t.Errorf("Authorization = %q, want %q", auth, "Basic key")
+		}
+		if appId := r.URL.Query().Get("app_id"); appId != "app" {
+			t.Errorf("app_id = %q, want app", appId)
+		}
+		switch offset := r.URL.Query().Get("offset"); offset {
+		case "0":
+			fmt.Fprint(w, `{"total_count":3,"notifications":[{"id":"n1"},{"id":"n2"}]}`)
+		case "2":
+			fmt.Fprint(w, `{"total_count":3,"notifications":[{"id":"n3","send_after":1443117600}]}`)
+		default:
+			t.Errorf("Unexpected offset %s", offset)
+			fmt.Fprint(w, `{"total_count":3,"notifications":[]}`)
+		}
+	})
+	notifications, err := client.Notifications.All("app", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 || len(notifications) != 3 {
+		t.Fatalf("All() made %d requests, returned %+v", requests, notifications)
+	}
+	if n := notifications[2]; n.Id != "n3" || n.Notification.Id != "n3" || n.AppId != "app" || n.ScheduledAt != 1443117600 {
+		t.Errorf("Last notification = %+v", n)
+	}
+}
+
+func TestNotificationsService_All_shortPage(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	requests := 0
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 1 {
+			fmt.Fprint(w, `{"total_count":5,"notifications":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"total_count":5,"notifications":[{"id":"n1"}]}`)
+	})
+	notifications, err := client.Notifications.All("app", "key")
+	if err != nil || len(notifications) != 1 || requests != 2 {
+		t.Errorf("All() = %+v, %v after %d requests", notifications, err, requests)
+	}
+	if _, err := client.Notifications.List("", "key", 10, 0); err == nil {
+		t.Error("List without app id expected error")
+	}
+}
+
+func TestNotificationsService_Cancel(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	canceled := false
+	mux.HandleFunc("/notifications/n1", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" {
+			t.Errorf("Request method = %v, want DELETE", r.Method)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Basic key" {
+			t.Errorf("Authorization = %q, want %q", auth, "Basic key")
+		}
+		if appId := r.URL.Query().Get("app_id"); appId != "app" {
+			t.Errorf("app_id = %q, want app", appId)
+		}
+		canceled = true
+		fmt.Fprint(w, `{"success":true}`)
+	})
+	if err := client.Notifications.Cancel("app", "n1", "key"); err != nil || !canceled {
+		t.Errorf("Cancel = %v, canceled = %v", err, canceled)
+	}
+	if err := client.Notifications.Cancel("app", "", "key"); err == nil {
+		t.Error("Cancel without notification id expected error")
+	}
+}
@@ -0,0 +1,186 @@
+package gamethrive
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// OpenEvent describes how a player interacted with a notification.
+type OpenEvent struct {
+	NotificationId string `json:"-"`
+	AppId          string `json:"app_id"`
+	Opened         bool   `json:"opened"`
+	PlayerId       string `json:"player_id,omitempty"`
+	// ActionId is the id of the action button clicked, if any.
+	ActionId  string `json:"action_id,omitempty"`
+	LaunchURL string `json:"launch_url,omitempty"`
+	// SentAt and OpenedAt give the time to open; they are not sent.
+	SentAt   time.Time `json:"-"`
+	OpenedAt time.Time `json:"-"`
+}
+
+// TimeToOpen returns how long after being sent the notification was
+// opened, or zero if unknown.
+func (e *OpenEvent) TimeToOpen() time.Duration {
+	if e.SentAt.IsZero() || e.OpenedAt.IsZero() || e.OpenedAt.Before(e.SentAt) {
+		return 0
+	}
+	return e.OpenedAt.Sub(e.SentAt)
+}
+
+// TrackOpen reports an open like Open, including the player, the clicked
+// button and launch url.
+func (s *NotificationsService) TrackOpen(event *OpenEvent) error {
+	if len(event.NotificationId) <= 0 {
+		return errors.New("Notification id is required")
+	}
+	req, err := s.c.NewRequest("PUT", "notifications/"+event.NotificationId, event)
+	if err != nil {
+		return err
+	}
+	_, err = s.c.Do(req, nil)
+	return err
+}
+
+// OpenStats are the aggregated opens of a notification or template.
+type OpenStats struct {
+	Sent   int
+	Opened int
+	// Actions counts clicks per action button id.
+	Actions map[string]int
+	// TotalTimeToOpen is the sum of the known times to open, over TimedOpens
+	// opens.
+	TotalTimeToOpen time.Duration
+	TimedOpens      int
+}
+
+// OpenRate returns the ratio of opened over sent notifications.
+func (s *OpenStats) OpenRate() float64 {
+	if s.Sent <= 0 {
+		return 0
+	}
+	return float64(s.Opened) / float64(s.Sent)
+}
+
+// AverageTimeToOpen returns the mean of the known times to open.
+func (s *OpenStats) AverageTimeToOpen() time.Duration {
+	if s.TimedOpens <= 0 {
+		return 0
+	}
+	return s.TotalTimeToOpen / time.Duration(s.TimedOpens)
+}
+
+func (s *OpenStats) add(e *OpenEvent) {
+	if !e.Opened {
+		return
+	}
+	s.Opened++
+	if len(e.ActionId) > 0 {
+		if s.Actions == nil {
+			s.Actions = map[string]int{}
+		}
+		s.Actions[e.ActionId]++
+	}
+	if d := e.TimeToOpen(); d > 0 {
+		s.TotalTimeToOpen += d
+		s.TimedOpens++
+	}
+}
+
+// OpenAggregator rolls up sends and opens per notification and per
+// template, to compute open rates locally.
+type OpenAggregator struct {
+	mu            sync.Mutex
+	templates     map[string]string
+	sentAt        map[string]time.Time
+	notifications map[string]*OpenStats
+	byTemplate    map[string]*OpenStats
+}
+
+func NewOpenAggregator() *OpenAggregator {
+	return &OpenAggregator{
+		templates:     map[string]string{},
+		sentAt:        map[string]time.Time{},
+		notifications: map[string]*OpenStats{},
+		byTemplate:    map[string]*OpenStats{},
+	}
+}
+
+// RecordSent registers a sent notification, made from template (which may
+// be empty), with its number of recipients.
+func (a *OpenAggregator) RecordSent(notification *Notification, template string, recipients int, sentAt time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.templates[notification.Id] = template
+	a.sentAt[notification.Id] = sentAt
+	a.stats(a.notifications, notification.Id).Sent += recipients
+	if len(template) > 0 {
+		a.stats(a.byTemplate, template).Sent += recipients
+	}
+}
+
+// RecordOpen adds an open to its notification and template. Events without
+// SentAt take it from RecordSent.
+func (a *OpenAggregator) RecordOpen(event *OpenEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	e := *event
+	if e.SentAt.IsZero() {
+		e.SentAt = a.sentAt[e.NotificationId]
+	}
+	a.stats(a.notifications, e.NotificationId).add(&e)
+	if template := a.templates[e.NotificationId]; len(template) > 0 {
+		a.stats(a.byTemplate, template).add(&e)
+	}
+}
+
+// Notification returns a copy of the stats of a notification.
+func (a *OpenAggregator) Notification(id string) OpenStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return copyStats(a.notifications[id])
+}
+
+// Template returns a copy of the stats of a template.
+func (a *OpenAggregator) Template(name string) OpenStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return copyStats(a.byTemplate[name])
+}
+
+// Templates returns the names of the templates with recorded sends.
+func (a *OpenAggregator) Templates() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	names := make([]string, 0, len(a.byTemplate))
+	for name := range a.byTemplate {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a *OpenAggregator) stats(m map[string]*OpenStats, key string) *OpenStats {
+	s, ok := m[key]
+	if !ok {
+		s = new(OpenStats)
+		m[key] = s
+	}
+	return s
+}
+
+func copyStats(s *OpenStats) OpenStats {
+	if s == nil {
+		return OpenStats{}
+	}
+	c := *s
+	if s.Actions != nil {
+		c.Actions = map[string]int{}
+		for k, v := range s.Actions {
+			c.Actions[k] = v
+		}
+	}
+	return c
+}
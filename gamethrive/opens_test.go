@@ -0,0 +1,47 @@
+package gamethrive
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNotificationsService_TrackOpen(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	mux.HandleFunc("/notifications/n1", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" {
+			t.Errorf("Method = %s, want PUT", r.Method)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["action_id"] != "buy" || body["player_id"] != "p1" || body["opened"] != true {
+			t.Errorf("Body = %v", body)
+		}
+		w.Write([]byte(`{"success":true}`))
+	})
+	err := client.Notifications.TrackOpen(&OpenEvent{NotificationId: "n1", AppId: "app", Opened: true, PlayerId: "p1", ActionId: "buy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenAggregator(t *testing.T) {
+	sent := time.Date(2015, 9, 24, 12, 0, 0, 0, time.UTC)
+	a := NewOpenAggregator()
+	a.RecordSent(&Notification{Id: "n1"}, "welcome", 4, sent)
+	a.RecordSent(&Notification{Id: "n2"}, "welcome", 6, sent)
+	a.RecordOpen(&OpenEvent{NotificationId: "n1", Opened: true, ActionId: "play", OpenedAt: sent.Add(time.Minute)})
+	a.RecordOpen(&OpenEvent{NotificationId: "n2", Opened: true, OpenedAt: sent.Add(3 * time.Minute)})
+	a.RecordOpen(&OpenEvent{NotificationId: "n2", Opened: false})
+
+	n1 := a.Notification("n1")
+	if n1.OpenRate() != 0.25 || n1.Actions["play"] != 1 {
+		t.Errorf("Notification(n1) = %+v", n1)
+	}
+	welcome := a.Template("welcome")
+	if welcome.Sent != 10 || welcome.Opened != 2 || welcome.AverageTimeToOpen() != 2*time.Minute {
+		t.Errorf("Template(welcome) = %+v", welcome)
+	}
+}
@@ -0,0 +1,484 @@
+package gamethrive
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type OutboxKind string
+
+const (
+	OutboxNotification OutboxKind = "notification"
+	OutboxSession      OutboxKind = "session"
+	OutboxAmount       OutboxKind = "amount"
+	OutboxPlaytime     OutboxKind = "playtime"
+)
+
+var ErrDuplicate = errors.New("Duplicate outbox entry")
+
+// OutboxEntry is a pending API call stored on disk.
+type OutboxEntry struct {
+	Id          string          `json:"id"`
+	Key         string          `json:"key,omitempty"`
+	Kind        OutboxKind      `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	CreatedAt   time.Time       `json:"created_at"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	DeliveredAt time.Time       `json:"delivered_at,omitempty"`
+	LastError   string          `json:"last_error,omitempty"`
+	ResultId    string          `json:"result_id,omitempty"`
+}
+
+type outboxAmount struct {
+	PlayerId string  `json:"player_id"`
+	Amount   float64 `json:"amount"`
+}
+
+type outboxPlaytime struct {
+	PlayerId string        `json:"player_id"`
+	State    PlaytimeState `json:"state"`
+	Time     int           `json:"active_time"`
+}
+
+const (
+	outboxPending  = "pending"
+	outboxInflight = "inflight"
+	outboxDead     = "dead"
+	outboxDone     = "done"
+)
+
+var outboxFolders = []string{outboxPending, outboxInflight, outboxDead, outboxDone}
+
+// Outbox persists notification and player calls in a directory before
+// delivering them, so they are not lost if the process dies or the API is
+// unreachable. Entries are retried with exponential backoff and moved to a
+// dead letter folder when they fail permanently or run out of attempts.
+// Entries being delivered are moved to an in-flight folder, so concurrent
+// deliveries never send the same entry twice. A directory must be used by
+// a single Outbox.
+type Outbox struct {
+	c   *Client
+	dir string
+
+	// Auth is the API key sent with notification entries. It is never
+	// stored in the outbox.
+	Auth string
+	// Workers is the number of concurrent deliveries, defaults to 4.
+	Workers int
+	// MaxAttempts before an entry is moved to dead letters, defaults to 10.
+	MaxAttempts int
+	// PollInterval between checks for due entries, defaults to 5 seconds.
+	PollInterval time.Duration
+	// DedupWindow is how long keys of delivered entries are remembered,
+	// defaults to 24 hours.
+	DedupWindow time.Duration
+	// OnDeliver, when set, is called after every delivery attempt.
+	OnDeliver func(entry *OutboxEntry, err error)
+
+	mu       sync.Mutex
+	inflight map[string]bool
+}
+
+// NewOutbox creates an outbox stored at dir, creating it if needed.
+func NewOutbox(client *Client, dir string) (*Outbox, error) {
+	for _, sub := range outboxFolders {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return nil, err
+		}
+	}
+	return &Outbox{
+		c:            client,
+		dir:          dir,
+		Workers:      4,
+		MaxAttempts:  10,
+		PollInterval: 5 * time.Second,
+		DedupWindow:  24 * time.Hour,
+		inflight:     map[string]bool{},
+	}, nil
+}
+
+// EnqueueNotification stores a Notifications.New call. If key is not empty
+// and an entry with the same key is pending, dead or was delivered within
+// DedupWindow, the existing entry is returned along with ErrDuplicate.
+func (o *Outbox) EnqueueNotification(key string, notification *Notification) (*OutboxEntry, error) {
+	return o.enqueue(key, OutboxNotification, notification)
+}
+
+// EnqueueSession stores a Players.Session call.
+func (o *Outbox) EnqueueSession(key string, player *Player) (*OutboxEntry, error) {
+	if len(player.Id) <= 0 {
+		return nil, errors.New("Player id is required")
+	}
+	return o.enqueue(key, OutboxSession, player)
+}
+
+// EnqueueAmount stores a Players.UpdateAmount call.
+func (o *Outbox) EnqueueAmount(key string, playerId string, amount float64) (*OutboxEntry, error) {
+	if len(playerId) <= 0 {
+		return nil, errors.New("Player id is required")
+	}
+	return o.enqueue(key, OutboxAmount, outboxAmount{playerId, amount})
+}
+
+// EnqueuePlaytime stores a Players.Playtime call.
+func (o *Outbox) EnqueuePlaytime(key string, playerId string, state PlaytimeState, time int) (*OutboxEntry, error) {
+	if len(playerId) <= 0 {
+		return nil, errors.New("Player id is required")
+	}
+	return o.enqueue(key, OutboxPlaytime, outboxPlaytime{playerId, state, time})
+}
+
+func (o *Outbox) enqueue(key string, kind OutboxKind, payload interface{}) (*OutboxEntry, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	id, err := outboxId(key)
+	if err != nil {
+		return nil, err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(key) > 0 {
+		for _, sub := range outboxFolders {
+			entry, err := o.read(sub, id)
+			if err == nil {
+				return entry, ErrDuplicate
+			}
+			if !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+	}
+	now := time.Now()
+	entry := &OutboxEntry{
+		Id:          id,
+		Key:         key,
+		Kind:        kind,
+		Payload:     data,
+		CreatedAt:   now,
+		NextAttempt: now,
+	}
+	return entry, o.write(outboxPending, entry)
+}
+
+// Pending returns the entries waiting to be delivered.
+func (o *Outbox) Pending() ([]*OutboxEntry, error) {
+	return o.list(outboxPending)
+}
+
+// DeadLetters returns the entries that could not be delivered.
+func (o *Outbox) DeadLetters() ([]*OutboxEntry, error) {
+	return o.list(outboxDead)
+}
+
+// Requeue moves a dead letter back to the pending queue, resetting its
+// attempts.
+func (o *Outbox) Requeue(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entry, err := o.read(outboxDead, id)
+	if err != nil {
+		return err
+	}
+	entry.Attempts = 0
+	entry.NextAttempt = time.Now()
+	if err := o.write(outboxPending, entry); err != nil {
+		return err
+	}
+	return os.Remove(o.path(outboxDead, id))
+}
+
+// Discard removes a dead letter.
+func (o *Outbox) Discard(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return os.Remove(o.path(outboxDead, id))
+}
+
+// Recover moves the entries left in flight by a process that died back to
+// the pending queue.
+func (o *Outbox) Recover() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entries, err := o.list(outboxInflight)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if o.inflight[entry.Id] {
+			continue
+		}
+		if err := o.move(outboxInflight, outboxPending, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run recovers the entries left in flight and then delivers due entries
+// every PollInterval until stop is closed.
+func (o *Outbox) Run(stop <-chan struct{}) error {
+	if err := o.Recover(); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(o.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := o.Deliver(time.Now()); err != nil {
+			return err
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Deliver attempts every pending entry due at now and waits for the
+// attempts to finish.
+func (o *Outbox) Deliver(now time.Time) error {
+	if err := o.prune(now); err != nil {
+		return err
+	}
+	entries, err := o.claim(now)
+	if err != nil {
+		return err
+	}
+	workers := o.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	queue := make(chan *OutboxEntry)
+	errs := make(chan error, len(entries))
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for entry := range queue {
+				errs <- o.attempt(entry, now)
+			}
+		}()
+	}
+	for _, entry := range entries {
+		queue <- entry
+	}
+	close(queue)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attempt sends an entry and stores the outcome. Only storage failures are
+// returned, API failures are recorded in the entry.
+func (o *Outbox) attempt(entry *OutboxEntry, now time.Time) error {
+	resultId, err := o.send(entry)
+	storeErr := o.record(entry, resultId, err, now)
+	if o.OnDeliver != nil {
+		o.OnDeliver(entry, err)
+	}
+	return storeErr
+}
+
+// claim moves the pending entries due at now to the in-flight folder and
+// returns them. Entries claimed by another delivery are skipped.
+func (o *Outbox) claim(now time.Time) ([]*OutboxEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entries, err := o.list(outboxPending)
+	if err != nil {
+		return nil, err
+	}
+	var claimed []*OutboxEntry
+	for _, entry := range entries {
+		if entry.NextAttempt.After(now) {
+			continue
+		}
+		err := os.Rename(o.path(outboxPending, entry.Id), o.path(outboxInflight, entry.Id))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		o.inflight[entry.Id] = true
+		claimed = append(claimed, entry)
+	}
+	return claimed, nil
+}
+
+func (o *Outbox) record(entry *OutboxEntry, resultId string, err error, now time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.inflight, entry.Id)
+	entry.Attempts++
+	if err == nil {
+		entry.LastError = ""
+		entry.ResultId = resultId
+		entry.DeliveredAt = now
+		return o.move(outboxInflight, outboxDone, entry)
+	}
+	entry.LastError = err.Error()
+	if isPermanent(err) || entry.Attempts >= o.MaxAttempts {
+		return o.move(outboxInflight, outboxDead, entry)
+	}
+	entry.NextAttempt = now.Add(outboxBackoff(entry.Attempts))
+	return o.move(outboxInflight, outboxPending, entry)
+}
+
+func (o *Outbox) send(entry *OutboxEntry) (string, error) {
+	switch entry.Kind {
+	case OutboxNotification:
+		var notification Notification
+		if err := json.Unmarshal(entry.Payload, &notification); err != nil {
+			return "", err
+		}
+		_, err := o.c.Notifications.New(&notification, o.Auth)
+		if err == ErrDuplicateNotification {
+			err = nil
+		}
+		return notification.Id, err
+	case OutboxSession:
+		var player Player
+		if err := json.Unmarshal(entry.Payload, &player); err != nil {
+			return "", err
+		}
+		return "", o.c.Players.Session(&player)
+	case OutboxAmount:
+		var body outboxAmount
+		if err := json.Unmarshal(entry.Payload, &body); err != nil {
+			return "", err
+		}
+		return "", o.c.Players.UpdateAmount(body.PlayerId, body.Amount)
+	case OutboxPlaytime:
+		var body outboxPlaytime
+		if err := json.Unmarshal(entry.Payload, &body); err != nil {
+			return "", err
+		}
+		return "", o.c.Players.Playtime(body.PlayerId, body.State, body.Time)
+	}
+	return "", fmt.Errorf("Unknown outbox entry kind %q", entry.Kind)
+}
+
+func (o *Outbox) prune(now time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entries, err := o.list(outboxDone)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if now.Sub(entry.DeliveredAt) > o.DedupWindow {
+			if err := os.Remove(o.path(outboxDone, entry.Id)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (o *Outbox) list(sub string) ([]*OutboxEntry, error) {
+	files, err := ioutil.ReadDir(filepath.Join(o.dir, sub))
+	if err != nil {
+		return nil, err
+	}
+	var entries []*OutboxEntry
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		entry, err := o.read(sub, strings.TrimSuffix(f.Name(), ".json"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+func (o *Outbox) read(sub, id string) (*OutboxEntry, error) {
+	data, err := ioutil.ReadFile(o.path(sub, id))
+	if err != nil {
+		return nil, err
+	}
+	entry := new(OutboxEntry)
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, fmt.Errorf("Invalid outbox entry %s: %s", id, err.Error())
+	}
+	return entry, nil
+}
+
+func (o *Outbox) write(sub string, entry *OutboxEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(o.path(sub, entry.Id), data)
+}
+
+func (o *Outbox) move(from, to string, entry *OutboxEntry) error {
+	if err := o.write(to, entry); err != nil {
+		return err
+	}
+	return os.Remove(o.path(from, entry.Id))
+}
+
+func (o *Outbox) path(sub, id string) string {
+	return filepath.Join(o.dir, sub, filepath.Base(id)+".json")
+}
+
+func outboxId(key string) (string, error) {
+	if len(key) > 0 {
+		sum := sha1.Sum([]byte(key))
+		return hex.EncodeToString(sum[:]), nil
+	}
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func outboxBackoff(attempts int) time.Duration {
+	d := time.Second << uint(attempts)
+	if d <= 0 || d > time.Hour {
+		return time.Hour
+	}
+	return d
+}
+
+// isPermanent reports whether retrying a request that failed with err is
+// pointless, which is the case for client errors other than rate limits.
+func isPermanent(err error) bool {
+	res, ok := err.(*ErrorResponse)
+	if !ok || res.Response == nil {
+		return false
+	}
+	return res.StatusCode >= 400 && res.StatusCode < 500 && res.StatusCode != http.StatusTooManyRequests
+}
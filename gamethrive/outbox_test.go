@@ -0,0 +1,136 @@
+package gamethrive
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOutbox_Deliver(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"n1","recipients":1}`)
+	})
+	mux.HandleFunc("/players/bad/on_purchase", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errors":["Player not found"]}`, http.StatusBadRequest)
+	})
+	mux.HandleFunc("/players/p1/on_focus", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	o, err := NewOutbox(client, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := o.EnqueueNotification("welcome", &Notification{AppId: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dup, err := o.EnqueueNotification("welcome", &Notification{AppId: "app"}); err != ErrDuplicate || dup.Id != first.Id {
+		t.Errorf("EnqueueNotification duplicate = %v, %v, want %v, %v", dup, err, first.Id, ErrDuplicate)
+	}
+	o.EnqueueAmount("", "bad", 1.5)
+	o.EnqueuePlaytime("", "p1", Suspend, 60)
+
+	now := time.Now()
+	if err := o.Deliver(now); err != nil {
+		t.Fatal(err)
+	}
+	if dup, err := o.EnqueueNotification("welcome", &Notification{AppId: "app"}); err != ErrDuplicate || dup.ResultId != "n1" {
+		t.Errorf("Delivered entry = %v, %v, want result id n1", dup, err)
+	}
+	dead, _ := o.DeadLetters()
+	if len(dead) != 1 || dead[0].Kind != OutboxAmount {
+		t.Errorf("DeadLetters = %v, want the amount entry", dead)
+	}
+	pending, _ := o.Pending()
+	if len(pending) != 1 || pending[0].Kind != OutboxPlaytime || pending[0].Attempts != 1 || !pending[0].NextAttempt.After(now) {
+		t.Errorf("Pending = %v, want the playtime entry rescheduled", pending)
+	}
+
+	if err := o.Requeue(dead[0].Id); err != nil {
+		t.Fatal(err)
+	}
+	pending, _ = o.Pending()
+	if len(pending) != 2 {
+		t.Errorf("Pending after Requeue has %d entries, want 2", len(pending))
+	}
+}
+
+func TestOutbox_Deliver_concurrent(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	var mu sync.Mutex
+	sent := 0
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Basic key" {
+			t.Errorf("Authorization = %q, want %q", auth, "Basic key")
+		}
+		mu.Lock()
+		sent++
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprint(w, `{"id":"n1","recipients":1}`)
+	})
+	dir := t.TempDir()
+	o, err := NewOutbox(client, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.Auth = "key"
+	entry, err := o.EnqueueNotification("", &Notification{AppId: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(dir, outboxPending, entry.Id+".json"))
+	if strings.Contains(string(data), "key") {
+		t.Errorf("Outbox entry %s stores the auth key", data)
+	}
+	now := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := o.Deliver(now); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if sent != 1 {
+		t.Errorf("Concurrent deliveries sent the entry %d times, want 1", sent)
+	}
+}
+
+func TestOutbox_Recover(t *testing.T) {
+	o, err := NewOutbox(NewClient(nil), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, _ := o.EnqueueAmount("", "p1", 1)
+	if _, err := o.claim(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ := o.Pending(); len(pending) != 0 {
+		t.Fatalf("Pending after claim = %v, want none", pending)
+	}
+	if err := o.Recover(); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ := o.Pending(); len(pending) != 0 {
+		t.Errorf("Recover moved an entry still in flight: %v", pending)
+	}
+	restarted, _ := NewOutbox(NewClient(nil), o.dir)
+	if err := restarted.Recover(); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ := restarted.Pending(); len(pending) != 1 || pending[0].Id != entry.Id {
+		t.Errorf("Pending after Recover = %v, want %s", pending, entry.Id)
+	}
+}
@@ -0,0 +1,207 @@
+package gamethrive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Keys of Notification.Data used to recognize the notifications created
+// from a CampaignFile.
+const (
+	CampaignDataKey = "campaign"
+	CampaignHashKey = "campaign_hash"
+)
+
+// CampaignFile declares the notifications an application should have
+// scheduled. Campaigns with a Spec are recurring; those without one are
+// sent once, at their notification SendAfter.
+type CampaignFile struct {
+	AppId     string                  `json:"app_id"`
+	Templates map[string]Notification `json:"templates,omitempty"`
+	Campaigns []Campaign              `json:"campaigns"`
+}
+
+func LoadCampaignFile(path string) (*CampaignFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	f := new(CampaignFile)
+	if err := json.NewDecoder(file).Decode(f); err != nil {
+		return nil, fmt.Errorf("Invalid campaign file %s: %s", path, err.Error())
+	}
+	return f, nil
+}
+
+type PlanAction string
+
+const (
+	PlanCreate PlanAction = "create"
+	PlanCancel PlanAction = "cancel"
+)
+
+// PlanStep is a change needed to reach the declared state. Cancel steps
+// have the Id of the notification to cancel.
+type PlanStep struct {
+	Action       PlanAction
+	Campaign     string
+	SendAt       time.Time
+	Id           string
+	Notification *Notification
+}
+
+type Plan struct {
+	AppId     string
+	Steps     []PlanStep
+	Unchanged int
+}
+
+// Desired returns the notifications the file declares between now and
+// now+horizon, with their campaign marks in Data.
+func (f *CampaignFile) Desired(now time.Time, horizon time.Duration) ([]Notification, error) {
+	var desired []Notification
+	for i := range f.Campaigns {
+		c := &f.Campaigns[i]
+		if len(c.Name) <= 0 {
+			return nil, fmt.Errorf("Campaign %d has no name", i+1)
+		}
+		n := c.Notification
+		if len(c.Template) > 0 {
+			template, ok := f.Templates[c.Template]
+			if !ok {
+				return nil, fmt.Errorf("Campaign %q uses unknown template %q", c.Name, c.Template)
+			}
+			n = mergeNotification(template, n)
+		}
+		if len(n.AppId) <= 0 {
+			n.AppId = f.AppId
+		}
+		var times []time.Time
+		if len(c.Spec) > 0 {
+			cron, err := c.schedule()
+			if err != nil {
+				return nil, fmt.Errorf("Campaign %q: %s", c.Name, err.Error())
+			}
+			for t := cron.Next(now); !t.IsZero() && t.Before(now.Add(horizon)); t = cron.Next(t) {
+				times = append(times, t)
+			}
+		} else if n.SendAfter != nil {
+			if n.SendAfter.After(now) && n.SendAfter.Before(now.Add(horizon)) {
+				times = append(times, n.SendAfter.Time)
+			}
+		} else {
+			return nil, fmt.Errorf("Campaign %q has neither spec nor send_after", c.Name)
+		}
+		for _, t := range times {
+			scheduled := n
+			scheduled.SendAfter = &SendTime{t}
+			scheduled.Data = map[string]string{}
+			for k, v := range n.Data {
+				scheduled.Data[k] = v
+			}
+			scheduled.Data[CampaignDataKey] = c.Name
+			scheduled.Data[CampaignHashKey] = NotificationHash(&scheduled)
+			desired = append(desired, scheduled)
+		}
+	}
+	return desired, nil
+}
+
+// Plan compares the notifications declared until now+horizon with the
+// existing ones, as returned by Notifications.All. Pending notifications
+// created from a campaign that are no longer declared, or that changed,
+// are canceled. Notifications scheduled after now+horizon are left alone.
+func (f *CampaignFile) Plan(existing []SentNotification, now time.Time, horizon time.Duration) (*Plan, error) {
+	if len(f.AppId) <= 0 {
+		return nil, errors.New("App id is required")
+	}
+	desired, err := f.Desired(now, horizon)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{AppId: f.AppId}
+	pending := map[string]*SentNotification{}
+	end := now.Add(horizon).Unix()
+	for i := range existing {
+		n := &existing[i]
+		at := int64(n.ScheduledAt)
+		if n.Canceled || at <= now.Unix() || at >= end || len(n.Data[CampaignDataKey]) <= 0 {
+			continue
+		}
+		pending[n.Data[CampaignHashKey]] = n
+	}
+	for i := range desired {
+		n := &desired[i]
+		hash := n.Data[CampaignHashKey]
+		if _, ok := pending[hash]; ok {
+			delete(pending, hash)
+			plan.Unchanged++
+			continue
+		}
+		plan.Steps = append(plan.Steps, PlanStep{
+			Action:       PlanCreate,
+			Campaign:     n.Data[CampaignDataKey],
+			SendAt:       n.SendAfter.Time,
+			Notification: n,
+		})
+	}
+	for _, n := range pending {
+		plan.Steps = append(plan.Steps, PlanStep{
+			Action:   PlanCancel,
+			Campaign: n.Data[CampaignDataKey],
+			SendAt:   time.Unix(int64(n.ScheduledAt), 0),
+			Id:       n.Id,
+		})
+	}
+	sort.SliceStable(plan.Steps, func(i, j int) bool {
+		a, b := plan.Steps[i], plan.Steps[j]
+		if a.Action != b.Action {
+			return a.Action == PlanCancel
+		}
+		if !a.SendAt.Equal(b.SendAt) {
+			return a.SendAt.Before(b.SendAt)
+		}
+		return a.Campaign < b.Campaign
+	})
+	return plan, nil
+}
+
+// Apply runs the steps of the plan in order, cancelling before creating.
+// It stops at the first error, returning the steps applied so far.
+func (p *Plan) Apply(client *Client, auth string) (int, error) {
+	for i, step := range p.Steps {
+		var err error
+		switch step.Action {
+		case PlanCancel:
+			err = client.Notifications.Cancel(p.AppId, step.Id, auth)
+		case PlanCreate:
+			n := *step.Notification
+			_, err = client.Notifications.New(&n, auth)
+			step.Id = n.Id
+			p.Steps[i] = step
+		}
+		if err != nil {
+			return i, fmt.Errorf("Campaign %q: %s", step.Campaign, err.Error())
+		}
+	}
+	return len(p.Steps), nil
+}
+
+// mergeNotification returns template with the non zero fields of n.
+func mergeNotification(template, n Notification) Notification {
+	merged := template
+	dst := reflect.ValueOf(&merged).Elem()
+	src := reflect.ValueOf(n)
+	for i := 0; i < src.NumField(); i++ {
+		if !src.Field(i).IsZero() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	return merged
+}
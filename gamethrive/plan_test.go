@@ -0,0 +1,53 @@
+package gamethrive
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCampaignFile_Plan(t *testing.T) {
+	now := time.Date(2015, 9, 24, 12, 0, 0, 0, time.UTC)
+	f := &CampaignFile{
+		AppId: "app",
+		Templates: map[string]Notification{
+			"promo": {Contents: map[string]string{"en": "Weekend sale!"}, IncludedSegments: []string{"All"}},
+		},
+		Campaigns: []Campaign{
+			{Name: "weekend", Spec: "0 18 * * 5", Template: "promo", Notification: Notification{Data: map[string]string{"sale": "1"}}},
+			{Name: "launch", Notification: Notification{Contents: map[string]string{"en": "Out now"}, SendAfter: &SendTime{now.Add(48 * time.Hour)}}},
+			{Name: "anniversary", Notification: Notification{Contents: map[string]string{"en": "One year"}, SendAfter: &SendTime{now.Add(365 * 24 * time.Hour)}}},
+		},
+	}
+	desired, err := f.Desired(now, 14*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(desired) != 3 || desired[0].Contents["en"] != "Weekend sale!" || desired[0].Data["sale"] != "1" {
+		t.Fatalf("Desired() = %+v", desired)
+	}
+
+	existing := []SentNotification{
+		// Still declared.
+		{Notification: Notification{Data: desired[0].Data}, Id: "keep", ScheduledAt: int(desired[0].SendAfter.Unix())},
+		// Content changed since it was created.
+		{Notification: Notification{Data: map[string]string{CampaignDataKey: "launch", CampaignHashKey: "old"}}, Id: "stale", ScheduledAt: int(now.Add(48 * time.Hour).Unix())},
+		// Already sent, or not managed by the file.
+		{Notification: Notification{Data: map[string]string{CampaignDataKey: "weekend", CampaignHashKey: "x"}}, Id: "sent", ScheduledAt: int(now.Add(-time.Hour).Unix())},
+		{Id: "manual", ScheduledAt: int(now.Add(time.Hour).Unix())},
+		// Scheduled beyond the horizon.
+		{Notification: Notification{Data: map[string]string{CampaignDataKey: "anniversary", CampaignHashKey: "y"}}, Id: "later", ScheduledAt: int(now.Add(365 * 24 * time.Hour).Unix())},
+	}
+	plan, err := f.Plan(existing, now, 14*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Unchanged != 1 || len(plan.Steps) != 3 {
+		t.Fatalf("Plan() = %+v", plan)
+	}
+	if s := plan.Steps[0]; s.Action != PlanCancel || s.Id != "stale" {
+		t.Errorf("Steps[0] = %+v, want cancel of stale", s)
+	}
+	if s := plan.Steps[1]; s.Action != PlanCreate || s.Campaign != "launch" {
+		t.Errorf("Steps[1] = %+v, want create of launch", s)
+	}
+}
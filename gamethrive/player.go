@@ -3,6 +3,10 @@ package gamethrive
 import (
 	"errors"
 	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
 )
 
 type PlayersService struct {
@@ -22,6 +26,8 @@ type Player struct {
 	DeviceOS      string `json:"device_os,omitempty"`
 	GameVersion   string `json:"game_version,omitempty"`
 	AdvertisingId string `json:"ad_id,omitempty"`
+	// ExternalUserId is the player's id in your own backend.
+	ExternalUserId string `json:"external_user_id,omitempty"`
 	// Other/Optional fields
 	SessionCount int               `json:"session_count,omitempty"`
 	Tags         map[string]string `json:"tags,omitempty"`
@@ -31,13 +37,55 @@ type Player struct {
 	Playtime     int               `json:"playtime,omitempty"`
 }
 
-type DeviceType int
+func (p *Player) CreatedTime() time.Time {
+	return unixTime(p.CreatedAt)
+}
 
-const (
-	IOS     DeviceType = 0
-	Android DeviceType = 1
-	Amazon  DeviceType = 2
-)
+func (p *Player) SetCreatedTime(t time.Time) {
+	p.CreatedAt = int(t.Unix())
+}
+
+func (p *Player) LastActiveTime() time.Time {
+	return unixTime(p.LastActive)
+}
+
+func (p *Player) SetLastActiveTime(t time.Time) {
+	p.LastActive = int(t.Unix())
+}
+
+func (p *Player) PlaytimeDuration() time.Duration {
+	return time.Duration(p.Playtime) * time.Second
+}
+
+func (p *Player) SetPlaytimeDuration(d time.Duration) {
+	p.Playtime = int(d / time.Second)
+}
+
+func (p *Player) TimezoneOffset() time.Duration {
+	return time.Duration(p.Timezone) * time.Second
+}
+
+func (p *Player) SetTimezoneOffset(d time.Duration) {
+	p.Timezone = int(d / time.Second)
+}
+
+// Location returns a fixed zone for the player's timezone offset.
+func (p *Player) Location() *time.Location {
+	return time.FixedZone("", p.Timezone)
+}
+
+// SetLocation sets the timezone offset that loc has at t.
+func (p *Player) SetLocation(loc *time.Location, t time.Time) {
+	_, offset := t.In(loc).Zone()
+	p.Timezone = offset
+}
+
+func unixTime(sec int) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(sec), 0)
+}
 
 type PlaytimeState string
 
@@ -47,8 +95,20 @@ const (
 	Ping    PlaytimeState = "ping"
 )
 
-// Todo: test player id
+// New creates a player. When the client has an IdentityStore and the
+// device was already registered, the existing player id is reused and no
+// request is sent.
 func (s *PlayersService) New(player *Player) error {
+	if store := s.c.Identities; store != nil {
+		id, ok, err := lookupDevice(store, player)
+		if err != nil {
+			return err
+		}
+		if ok {
+			player.Id = id
+			return s.remember(player)
+		}
+	}
 	req, err := s.c.NewRequest("POST", "/players", player)
 	if err != nil {
 		return err
@@ -62,9 +122,89 @@ func (s *PlayersService) New(player *Player) error {
 		return err
 	}
 	player.Id = res.Id
+	return s.remember(player)
+}
+
+// remember records the player in the client IdentityStore, if any.
+func (s *PlayersService) remember(player *Player) error {
+	store := s.c.Identities
+	if store == nil {
+		return nil
+	}
+	for _, key := range deviceKeys(player) {
+		if err := store.SetPlayerId(key, player.Id); err != nil {
+			return err
+		}
+	}
+	if len(player.ExternalUserId) > 0 {
+		return store.AddExternalUserId(player.ExternalUserId, player.Id)
+	}
 	return nil
 }
 
+// playersPageSize is the number of players fetched per request when
+// listing every player.
+const playersPageSize = 300
+
+type PlayerList struct {
+	TotalCount int      `json:"total_count"`
+	Offset     int      `json:"offset"`
+	Limit      int      `json:"limit"`
+	Players    []Player `json:"players"`
+}
+
+// List returns a page of the players of an application. It requires the
+// "API Auth Key" of the application.
+func (s *PlayersService) List(appId string, auth string, limit, offset int) (*PlayerList, error) {
+	if len(appId) <= 0 {
+		return nil, errors.New("App id is required")
+	}
+	urlStr := fmt.Sprintf("players?app_id=%s&limit=%d&offset=%d", url.QueryEscape(appId), limit, offset)
+	req, err := s.c.NewRequest("GET", urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+	setAuth(req, auth)
+	list := new(PlayerList)
+	_, err = s.c.Do(req, list)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// All fetches every player of an application with Players.List.
+func (s *PlayersService) All(appId string, auth string) ([]Player, error) {
+	var players []Player
+	for offset := 0; ; {
+		list, err := s.List(appId, auth, playersPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, list.Players...)
+		offset += len(list.Players)
+		if len(list.Players) <= 0 || offset >= list.TotalCount {
+			return players, nil
+		}
+	}
+}
+
+func (s *PlayersService) Get(playerId string) (*Player, error) {
+	if len(playerId) <= 0 {
+		return nil, errors.New("Player id is required")
+	}
+	req, err := s.c.NewRequest("GET", "players/"+playerId, nil)
+	if err != nil {
+		return nil, err
+	}
+	player := new(Player)
+	_, err = s.c.Do(req, player)
+	if err != nil {
+		return nil, err
+	}
+	return player, nil
+}
+
 func (s *PlayersService) Update(player *Player) error {
 	if len(player.Id) <= 0 {
 		return errors.New("Player id is required")
@@ -78,6 +218,55 @@ func (s *PlayersService) Update(player *Player) error {
 	return err
 }
 
+// Patch sends only the fields of player that differ from previous, a
+// snapshot of the player as last known, including changes to zero values.
+// Tags missing from player are removed.
+func (s *PlayersService) Patch(previous, player *Player) error {
+	if len(player.Id) <= 0 {
+		return errors.New("Player id is required")
+	}
+	changes, err := PlayerChanges(previous, player)
+	if err != nil {
+		return err
+	}
+	if len(changes) <= 0 {
+		return nil
+	}
+	urlStr := fmt.Sprintf("players/%s", player.Id)
+	req, err := s.c.NewRequest("PUT", urlStr, changes)
+	if err != nil {
+		return err
+	}
+	_, err = s.c.Do(req, nil)
+	return err
+}
+
+// PlayerChanges returns the json fields that differ between previous and
+// player, keyed by their json name.
+func PlayerChanges(previous, player *Player) (map[string]interface{}, error) {
+	if previous == nil || player == nil {
+		return nil, errors.New("Previous and updated players are required")
+	}
+	changes := map[string]interface{}{}
+	pv, nv := reflect.ValueOf(previous).Elem(), reflect.ValueOf(player).Elem()
+	typ := nv.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if len(name) <= 0 || name == "-" || name == "id" || name == "tags" {
+			continue
+		}
+		a, b := pv.Field(i).Interface(), nv.Field(i).Interface()
+		if !reflect.DeepEqual(a, b) {
+			changes[name] = b
+		}
+	}
+	if tags := DiffTags(previous.Tags, player.Tags); !tags.Empty() {
+		changes["tags"] = tags.Tags()
+	}
+	return changes, nil
+}
+
 func (s *PlayersService) UpdateAmount(playerId string, amount float64) error {
 	if len(playerId) <= 0 {
 		return errors.New("Player id is required")
@@ -0,0 +1,82 @@
+package gamethrive
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPlayerChanges(t *testing.T) {
+	previous := &Player{
+		Id:           "p1",
+		AppId:        "app",
+		Language:     "en",
+		Timezone:     3600,
+		SessionCount: 4,
+		Tags:         map[string]string{"level": "3", "guild": "red"},
+	}
+	player := &Player{
+		Id:           "p1",
+		AppId:        "app",
+		Language:     "es",
+		Timezone:     0,
+		SessionCount: 0,
+		Tags:         map[string]string{"level": "3"},
+	}
+	want := map[string]interface{}{
+		"language":      "es",
+		"timezone":      0,
+		"session_count": 0,
+		"tags":          map[string]string{"guild": ""},
+	}
+	if got, err := PlayerChanges(previous, player); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("PlayerChanges = %#v, %v, want %#v", got, err, want)
+	}
+	if got, err := PlayerChanges(player, player); err != nil || len(got) != 0 {
+		t.Errorf("PlayerChanges of same player = %#v, %v, want none", got, err)
+	}
+	if _, err := PlayerChanges(nil, player); err == nil {
+		t.Error("PlayerChanges without previous player expected error")
+	}
+}
+
+func TestPlayer_accessors(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skip(err)
+	}
+	created := time.Date(2015, 9, 24, 12, 0, 0, 0, time.UTC)
+	p := new(Player)
+	if !p.CreatedTime().IsZero() || !p.LastActiveTime().IsZero() {
+		t.Errorf("Unset times = %v, %v, want zero", p.CreatedTime(), p.LastActiveTime())
+	}
+	p.SetCreatedTime(created)
+	p.SetLastActiveTime(created.Add(time.Hour))
+	p.SetPlaytimeDuration(90*time.Minute + 500*time.Millisecond)
+	summer, winter := *p, *p
+	summer.SetLocation(madrid, time.Date(2015, 7, 1, 0, 0, 0, 0, time.UTC))
+	winter.SetLocation(madrid, time.Date(2015, 12, 1, 0, 0, 0, 0, time.UTC))
+	_, offset := created.In(winter.Location()).Zone()
+	p.SetTimezoneOffset(-5*time.Hour - 30*time.Minute)
+	tests := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"CreatedAt", p.CreatedAt, 1443096000},
+		{"CreatedTime", p.CreatedTime().Equal(created), true},
+		{"LastActive", p.LastActive, 1443099600},
+		{"LastActiveTime", p.LastActiveTime().Equal(created.Add(time.Hour)), true},
+		{"Playtime", p.Playtime, 5400},
+		{"PlaytimeDuration", p.PlaytimeDuration(), 90 * time.Minute},
+		{"Timezone", p.Timezone, -19800},
+		{"TimezoneOffset", p.TimezoneOffset(), -5*time.Hour - 30*time.Minute},
+		{"SetLocation in summer", summer.Timezone, 7200},
+		{"SetLocation in winter", winter.Timezone, 3600},
+		{"Location", offset, 3600},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s = %v, want %v", test.name, test.got, test.want)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package gamethrive
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SendHistory records when notifications were sent to each player.
+type SendHistory interface {
+	// Count returns the notifications sent to a player since a time.
+	Count(playerId string, since time.Time) (int, error)
+	Record(playerId string, at time.Time) error
+}
+
+// historyRetention is how long MemorySendHistory keeps send times.
+const historyRetention = 7 * 24 * time.Hour
+
+// MemorySendHistory is a SendHistory holding the send times of each player
+// for a week, the longest window a daily cap looks at. Histories opened
+// with NewFileSendHistory survive restarts of the sender.
+type MemorySendHistory struct {
+	path  string
+	mu    sync.Mutex
+	sends map[string][]time.Time
+}
+
+func NewMemorySendHistory() *MemorySendHistory {
+	return &MemorySendHistory{sends: map[string][]time.Time{}}
+}
+
+// NewFileSendHistory opens the send times recorded at path, rewriting the
+// file on every Record. A missing file starts with no sends.
+func NewFileSendHistory(path string) (*MemorySendHistory, error) {
+	h := NewMemorySendHistory()
+	h.path = path
+	if err := loadJSONFile(path, "send history", &h.sends); err != nil {
+		return nil, err
+	}
+	if h.sends == nil {
+		h.sends = map[string][]time.Time{}
+	}
+	return h, nil
+}
+
+func (h *MemorySendHistory) Count(playerId string, since time.Time) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for _, t := range h.sends[playerId] {
+		if !t.Before(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (h *MemorySendHistory) Record(playerId string, at time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cutoff := at.Add(-historyRetention)
+	sends := h.sends[playerId][:0]
+	for _, t := range h.sends[playerId] {
+		if t.After(cutoff) {
+			sends = append(sends, t)
+		}
+	}
+	h.sends[playerId] = append(sends, at)
+	return saveJSONFile(h.path, h.sends)
+}
+
+// ErrMixedTarget is returned by SendPolicy.Send for notifications targeting
+// player ids along with segments, filters, device tokens or external user
+// ids, as the policy can only be applied to player ids.
+var ErrMixedTarget = errors.New("Player ids can not be combined with other targets")
+
+type SuppressReason string
+
+const (
+	QuietHours   SuppressReason = "quiet_hours"
+	FrequencyCap SuppressReason = "frequency_cap"
+)
+
+// Suppression is a recipient removed from a notification by a SendPolicy.
+// Deferred recipients have the time they will receive it in Until.
+type Suppression struct {
+	PlayerId string
+	Reason   SuppressReason
+	Until    time.Time
+}
+
+// PolicyResult reports what a SendPolicy did with a notification.
+type PolicyResult struct {
+	Recipients int
+	// NotificationIds holds the id of the immediate notification, if any,
+	// followed by those scheduled for deferred recipients.
+	NotificationIds []string
+	Suppressed      []Suppression
+	Deferred        []Suppression
+}
+
+// SendPolicy sits in front of Notifications.New and applies quiet hours
+// and a daily cap to each player in IncludedPlayerIds, in the player's own
+// timezone. Notifications targeting segments are sent unchanged, and those
+// mixing player ids with other targets are rejected with ErrMixedTarget.
+type SendPolicy struct {
+	c       *Client
+	history SendHistory
+
+	// QuietStart and QuietEnd are the local times of day, as offsets from
+	// midnight, between which players are not notified. The range may wrap
+	// midnight; equal values disable quiet hours.
+	QuietStart time.Duration
+	QuietEnd   time.Duration
+	// MaxPerDay caps the notifications a player gets in 24 hours. Zero
+	// means no cap.
+	MaxPerDay int
+	// Defer schedules notifications falling in quiet hours for the end of
+	// them, instead of dropping them.
+	Defer bool
+	Now   func() time.Time
+
+	mu        sync.Mutex
+	timezones map[string]int
+}
+
+func NewSendPolicy(client *Client, history SendHistory) *SendPolicy {
+	if history == nil {
+		history = NewMemorySendHistory()
+	}
+	return &SendPolicy{
+		c:         client,
+		history:   history,
+		Now:       time.Now,
+		timezones: map[string]int{},
+	}
+}
+
+// SetTimezones caches the timezones of players, which are otherwise fetched
+// with Players.Get.
+func (p *SendPolicy) SetTimezones(players []Player) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, player := range players {
+		p.timezones[player.Id] = player.Timezone
+	}
+}
+
+func (p *SendPolicy) timezone(playerId string) (*time.Location, error) {
+	p.mu.Lock()
+	offset, ok := p.timezones[playerId]
+	p.mu.Unlock()
+	if !ok {
+		player, err := p.c.Players.Get(playerId)
+		if err != nil {
+			return nil, err
+		}
+		offset = player.Timezone
+		p.mu.Lock()
+		p.timezones[playerId] = offset
+		p.mu.Unlock()
+	}
+	return time.FixedZone("", offset), nil
+}
+
+// quietUntil returns the end of the quiet hours containing t, or the zero
+// time if t is outside them.
+func (p *SendPolicy) quietUntil(t time.Time) time.Time {
+	if p.QuietStart == p.QuietEnd {
+		return time.Time{}
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	day := t.Sub(midnight)
+	if p.QuietStart < p.QuietEnd {
+		if day >= p.QuietStart && day < p.QuietEnd {
+			return midnight.Add(p.QuietEnd)
+		}
+		return time.Time{}
+	}
+	if day >= p.QuietStart {
+		return midnight.AddDate(0, 0, 1).Add(p.QuietEnd)
+	}
+	if day < p.QuietEnd {
+		return midnight.Add(p.QuietEnd)
+	}
+	return time.Time{}
+}
+
+// Send delivers notification to the players allowed by the policy. The
+// notification Id is set to the immediate delivery, if any.
+func (p *SendPolicy) Send(notification *Notification, auth string) (*PolicyResult, error) {
+	result := new(PolicyResult)
+	if len(notification.IncludedPlayerIds) > 0 && notification.targetsOthers() {
+		return nil, ErrMixedTarget
+	}
+	if len(notification.IncludedPlayerIds) <= 0 {
+		recipients, err := p.c.Notifications.New(notification, auth)
+		if err != nil && err != ErrDuplicateNotification {
+			return nil, err
+		}
+		result.Recipients = recipients
+		result.NotificationIds = []string{notification.Id}
+		return result, nil
+	}
+	now := p.Now()
+	var allowed []string
+	deferred := map[time.Time][]string{}
+	for _, id := range notification.IncludedPlayerIds {
+		if p.MaxPerDay > 0 {
+			count, err := p.history.Count(id, now.Add(-24*time.Hour))
+			if err != nil {
+				return nil, err
+			}
+			if count >= p.MaxPerDay {
+				result.Suppressed = append(result.Suppressed, Suppression{PlayerId: id, Reason: FrequencyCap})
+				continue
+			}
+		}
+		loc, err := p.timezone(id)
+		if err != nil {
+			return nil, err
+		}
+		until := p.quietUntil(now.In(loc))
+		switch {
+		case until.IsZero():
+			allowed = append(allowed, id)
+		case p.Defer:
+			until = until.UTC()
+			deferred[until] = append(deferred[until], id)
+			result.Deferred = append(result.Deferred, Suppression{PlayerId: id, Reason: QuietHours, Until: until})
+		default:
+			result.Suppressed = append(result.Suppressed, Suppression{PlayerId: id, Reason: QuietHours})
+		}
+	}
+
+	notification.Id = ""
+	if len(allowed) > 0 {
+		n := notification.forPlayers(allowed)
+		if err := p.send(&n, auth, now, result); err != nil {
+			return nil, err
+		}
+		notification.Id = n.Id
+	}
+	times := make([]time.Time, 0, len(deferred))
+	for t := range deferred {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	for _, t := range times {
+		n := notification.forPlayers(deferred[t])
+		sendAfter, err := newSendTime(t, now)
+		if err != nil {
+			return nil, err
+		}
+		n.SendAfter = sendAfter
+		if err := p.send(&n, auth, t, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// send delivers n and records it in the history of its players. A
+// notification stopped by the client DedupGuard was recorded when it was
+// first sent.
+func (p *SendPolicy) send(n *Notification, auth string, at time.Time, result *PolicyResult) error {
+	recipients, err := p.c.Notifications.New(n, auth)
+	if err != nil && err != ErrDuplicateNotification {
+		return err
+	}
+	result.Recipients += recipients
+	result.NotificationIds = append(result.NotificationIds, n.Id)
+	if err == ErrDuplicateNotification {
+		return nil
+	}
+	for _, id := range n.IncludedPlayerIds {
+		if err := p.history.Record(id, at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package gamethrive
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSendPolicy_Send(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	var sent []Notification
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		var n Notification
+		json.NewDecoder(r.Body).Decode(&n)
+		sent = append(sent, n)
+		fmt.Fprintf(w, `{"id":"n%d","recipients":%d}`, len(sent), len(n.IncludedPlayerIds))
+	})
+	mux.HandleFunc("/players/tokyo", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"tokyo","timezone":32400}`)
+	})
+
+	now := time.Date(2015, 9, 24, 14, 0, 0, 0, time.UTC)
+	history := NewMemorySendHistory()
+	history.Record("spammed", now.Add(-time.Hour))
+	p := NewSendPolicy(client, history)
+	p.Now = func() time.Time { return now }
+	p.QuietStart, p.QuietEnd = 22*time.Hour, 8*time.Hour
+	p.MaxPerDay = 1
+	p.Defer = true
+	p.SetTimezones([]Player{{Id: "madrid", Timezone: 7200}, {Id: "spammed"}})
+
+	mixed := &Notification{AppId: "app", IncludedPlayerIds: []string{"madrid"}, IncludedSegments: []string{"All"}}
+	if _, err := p.Send(mixed, ""); err != ErrMixedTarget {
+		t.Errorf("Send() of mixed targets error = %v, want %v", err, ErrMixedTarget)
+	}
+
+	n := &Notification{AppId: "app", IncludedPlayerIds: []string{"madrid", "tokyo", "spammed"}}
+	result, err := p.Send(n, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 2 || !reflect.DeepEqual(sent[0].IncludedPlayerIds, []string{"madrid"}) || n.Id != "n1" {
+		t.Fatalf("sent = %+v, Id = %q", sent, n.Id)
+	}
+	// 23:00 in Tokyo, deferred to 08:00 Tokyo time.
+	until := time.Date(2015, 9, 24, 23, 0, 0, 0, time.UTC)
+	if len(result.Deferred) != 1 || !result.Deferred[0].Until.Equal(until) || !sent[1].SendAfter.Equal(until) {
+		t.Errorf("Deferred = %+v, SendAfter = %v", result.Deferred, sent[1].SendAfter)
+	}
+	if len(result.Suppressed) != 1 || result.Suppressed[0] != (Suppression{PlayerId: "spammed", Reason: FrequencyCap}) {
+		t.Errorf("Suppressed = %+v", result.Suppressed)
+	}
+	if count, _ := history.Count("madrid", now.Add(-time.Hour)); count != 1 {
+		t.Errorf("history of madrid = %d, want 1", count)
+	}
+}
+
+func TestFileSendHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	h, err := NewFileSendHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2015, 9, 24, 14, 0, 0, 0, time.UTC)
+	h.Record("p1", now.Add(-8*24*time.Hour))
+	h.Record("p1", now)
+	reloaded, err := NewFileSendHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := reloaded.Count("p1", time.Time{}); count != 1 {
+		t.Errorf("Reloaded history has %d sends, want 1", count)
+	}
+}
@@ -0,0 +1,162 @@
+package gamethrive
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Approximate number of characters and buttons devices show in a
+// collapsed banner.
+const (
+	iosBannerLength      = 110
+	androidBannerLength  = 65
+	iosBannerButtons     = 2
+	androidBannerButtons = 3
+)
+
+// Banner is a mockup of a notification as shown by a device, in one
+// language.
+type Banner struct {
+	Platform string
+	Language string
+	// Title is the heading on Android, or the application name otherwise.
+	Title string
+	// Body is the text shown collapsed, Truncated when it does not fit.
+	Body      string
+	Truncated bool
+	Contents  string
+	Badge     string
+	Buttons   []Button
+	URL       string
+}
+
+// NotificationBanners returns the banners of notification for every
+// targeted platform and language, sorted by platform and language.
+func NotificationBanners(notification *Notification, appName string) []Banner {
+	langs := make([]string, 0, len(notification.Contents))
+	for lang := range notification.Contents {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	var banners []Banner
+	if notification.IsIOS || !notification.IsAndroid {
+		for _, lang := range langs {
+			b := newBanner(notification, "ios", lang, appName, iosBannerLength, iosBannerButtons)
+			b.Badge = badgeBehavior(notification)
+			banners = append(banners, b)
+		}
+	}
+	if notification.IsAndroid || !notification.IsIOS {
+		for _, lang := range langs {
+			b := newBanner(notification, "android", lang, appName, androidBannerLength, androidBannerButtons)
+			if heading := notification.Headings[lang]; len(heading) > 0 {
+				b.Title = heading
+			} else if heading := notification.Headings["en"]; len(heading) > 0 {
+				b.Title = heading
+			}
+			banners = append(banners, b)
+		}
+	}
+	return banners
+}
+
+func newBanner(n *Notification, platform, lang, appName string, length, buttons int) Banner {
+	b := Banner{
+		Platform: platform,
+		Language: lang,
+		Title:    appName,
+		Contents: n.Contents[lang],
+		URL:      n.URL,
+		Buttons:  n.Buttons,
+	}
+	runes := []rune(b.Contents)
+	b.Body = b.Contents
+	if len(runes) > length {
+		b.Body = strings.TrimSpace(string(runes[:length-1])) + "…"
+		b.Truncated = true
+	}
+	if len(b.Buttons) > buttons {
+		b.Buttons = b.Buttons[:buttons]
+	}
+	return b
+}
+
+func badgeBehavior(n *Notification) string {
+	switch n.IOSBadgeType {
+	case SetTo:
+		if n.IOSBadgeCount <= 0 {
+			return "cleared"
+		}
+		return fmt.Sprintf("set to %d", n.IOSBadgeCount)
+	case Increase:
+		return fmt.Sprintf("increased by %d", n.IOSBadgeCount)
+	}
+	return "unchanged"
+}
+
+// RenderBannersText writes a plain text mockup of banners.
+func RenderBannersText(w io.Writer, banners []Banner) error {
+	for _, b := range banners {
+		lines := []string{
+			fmt.Sprintf("[%s %s] %s", b.Platform, b.Language, b.Title),
+			"  " + b.Body,
+		}
+		if b.Truncated {
+			lines = append(lines, fmt.Sprintf("  (truncated, %d characters)", len([]rune(b.Contents))))
+		}
+		if len(b.Badge) > 0 {
+			lines = append(lines, "  Badge: "+b.Badge)
+		}
+		if len(b.Buttons) > 0 {
+			texts := make([]string, len(b.Buttons))
+			for i, button := range b.Buttons {
+				texts[i] = button.Text
+			}
+			lines = append(lines, "  Buttons: "+strings.Join(texts, " | "))
+		}
+		if len(b.URL) > 0 {
+			lines = append(lines, "  Opens: "+b.URL)
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(lines, "\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var bannersTemplate = template.Must(template.New("banners").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Notification preview</title>
+<style>
+body { font-family: sans-serif; background: #eee; margin: 2em; }
+.banner { width: 360px; margin: 0 0 1.5em; padding: 10px 12px; border-radius: 12px; box-shadow: 0 1px 3px rgba(0,0,0,.3); }
+.ios { background: rgba(30,30,30,.9); color: #fff; }
+.android { background: #fff; color: #212121; border-radius: 2px; }
+.meta { font-size: 11px; color: #888; margin-bottom: 4px; }
+.title { font-weight: bold; }
+.body { margin-top: 2px; white-space: pre-wrap; }
+.buttons { margin-top: 8px; border-top: 1px solid #ccc; padding-top: 6px; }
+.buttons span { margin-right: 16px; font-size: 13px; text-transform: uppercase; }
+.ios .buttons span { text-transform: none; }
+</style>
+</head>
+<body>
+{{range .}}<div class="banner {{.Platform}}" lang="{{.Language}}">
+<div class="meta">{{.Platform}} · {{.Language}}{{if .Badge}} · badge {{.Badge}}{{end}}{{if .Truncated}} · truncated{{end}}</div>
+<div class="title">{{.Title}}</div>
+<div class="body" title="{{.Contents}}">{{.Body}}</div>
+{{if .Buttons}}<div class="buttons">{{range .Buttons}}<span>{{.Text}}</span>{{end}}</div>
+{{end}}</div>
+{{end}}</body>
+</html>
+`))
+
+// RenderBannersHTML writes an html page with a mockup of banners.
+func RenderBannersHTML(w io.Writer, banners []Banner) error {
+	return bannersTemplate.Execute(w, banners)
+}
@@ -0,0 +1,36 @@
+package gamethrive
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRenderBannersText(t *testing.T) {
+	n := &Notification{
+		IsIOS:         true,
+		IsAndroid:     true,
+		Contents:      map[string]string{"en": "New levels!", "es": strings.Repeat("a", 100)},
+		Headings:      map[string]string{"en": "Update"},
+		Buttons:       []Button{{Id: "play", Text: "Play"}, {Id: "later", Text: "Later"}, {Id: "no", Text: "No"}},
+		IOSBadgeType:  Increase,
+		IOSBadgeCount: 1,
+	}
+	banners := NotificationBanners(n, "Game")
+	if len(banners) != 4 {
+		t.Fatalf("NotificationBanners() = %+v", banners)
+	}
+	buf := new(bytes.Buffer)
+	RenderBannersText(buf, banners[:1])
+	want := "[ios en] Game\n  New levels!\n  Badge: increased by 1\n  Buttons: Play | Later\n"
+	if buf.String() != want {
+		t.Errorf("RenderBannersText() = %q, want %q", buf.String(), want)
+	}
+	if es := banners[3]; es.Title != "Update" || !es.Truncated || len([]rune(es.Body)) != androidBannerLength {
+		t.Errorf("Android es banner = %+v", es)
+	}
+	buf.Reset()
+	if err := RenderBannersHTML(buf, banners); err != nil || !strings.Contains(buf.String(), `<div class="title">Update</div>`) {
+		t.Errorf("RenderBannersHTML() = %v\n%s", err, buf.String())
+	}
+}
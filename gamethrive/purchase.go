@@ -0,0 +1,234 @@
+package gamethrive
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PurchaseItem is a line of a purchase. Price is the unit price in
+// Currency, an ISO 4217 code. Negative quantities are refunds.
+type PurchaseItem struct {
+	SKU      string  `json:"sku"`
+	Quantity int     `json:"quantity"`
+	Price    float64 `json:"price"`
+	Currency string  `json:"currency"`
+}
+
+// Purchase is a set of items bought by a player.
+type Purchase struct {
+	Id       string         `json:"id"`
+	PlayerId string         `json:"player_id"`
+	Items    []PurchaseItem `json:"items"`
+	Time     time.Time      `json:"time"`
+	// RefundOf is the id of the purchase this one refunds, if any.
+	RefundOf string `json:"refund_of,omitempty"`
+	// USD is the converted total, set by PurchaseTracker.Track.
+	USD float64 `json:"usd"`
+}
+
+// RateSource converts currencies to USD.
+type RateSource interface {
+	// USDRate returns how many USD one unit of currency is worth.
+	USDRate(currency string) (float64, error)
+}
+
+// StaticRates is a RateSource backed by a fixed table.
+type StaticRates map[string]float64
+
+func (r StaticRates) USDRate(currency string) (float64, error) {
+	rate, ok := r[strings.ToUpper(currency)]
+	if !ok {
+		return 0, fmt.Errorf("No exchange rate for %s", currency)
+	}
+	return rate, nil
+}
+
+// OfflineRates is an approximate table for use when no live rate source is
+// available.
+var OfflineRates = StaticRates{
+	"USD": 1,
+	"EUR": 1.08,
+	"GBP": 1.27,
+	"JPY": 0.0067,
+	"CNY": 0.14,
+	"KRW": 0.00074,
+	"CAD": 0.73,
+	"AUD": 0.66,
+	"BRL": 0.18,
+	"MXN": 0.055,
+	"RUB": 0.011,
+	"INR": 0.012,
+	"CHF": 1.12,
+	"SEK": 0.095,
+}
+
+// PurchaseLedger stores tracked purchases.
+type PurchaseLedger interface {
+	Record(purchase *Purchase) error
+	Purchases(playerId string) ([]*Purchase, error)
+}
+
+// FileLedger is a PurchaseLedger appending json lines to a file.
+type FileLedger struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileLedger(path string) *FileLedger {
+	return &FileLedger{path: path}
+}
+
+func (l *FileLedger) Record(purchase *Purchase) error {
+	data, err := json.Marshal(purchase)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Purchases returns the purchases of a player, or every purchase if
+// playerId is empty.
+func (l *FileLedger) Purchases(playerId string) ([]*Purchase, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	file, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var purchases []*Purchase
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		p := new(Purchase)
+		if err := json.Unmarshal(scanner.Bytes(), p); err != nil {
+			return nil, fmt.Errorf("Invalid ledger entry in %s: %s", l.path, err.Error())
+		}
+		if len(playerId) <= 0 || p.PlayerId == playerId {
+			purchases = append(purchases, p)
+		}
+	}
+	return purchases, scanner.Err()
+}
+
+// PurchaseTracker converts purchases to USD, reports them with
+// Players.UpdateAmount and records them in a ledger.
+type PurchaseTracker struct {
+	c      *Client
+	rates  RateSource
+	ledger PurchaseLedger
+}
+
+// NewPurchaseTracker creates a tracker. A nil rates uses OfflineRates and
+// a nil ledger disables recording.
+func NewPurchaseTracker(client *Client, rates RateSource, ledger PurchaseLedger) *PurchaseTracker {
+	if rates == nil {
+		rates = OfflineRates
+	}
+	return &PurchaseTracker{c: client, rates: rates, ledger: ledger}
+}
+
+// Track converts a purchase to USD, sends it and records it. The amount is
+// rounded to cents; refunds are sent as negative amounts.
+func (t *PurchaseTracker) Track(purchase *Purchase) error {
+	if len(purchase.PlayerId) <= 0 {
+		return errors.New("Player id is required")
+	}
+	if len(purchase.Items) <= 0 {
+		return errors.New("Purchase has no items")
+	}
+	usd, err := t.Convert(purchase.Items)
+	if err != nil {
+		return err
+	}
+	purchase.USD = usd
+	if purchase.Time.IsZero() {
+		purchase.Time = time.Now()
+	}
+	if err := t.c.Players.UpdateAmount(purchase.PlayerId, usd); err != nil {
+		return err
+	}
+	if t.ledger == nil {
+		return nil
+	}
+	return t.ledger.Record(purchase)
+}
+
+// Refund tracks a purchase reverting every item of original.
+func (t *PurchaseTracker) Refund(original *Purchase, refundId string) (*Purchase, error) {
+	refund := &Purchase{
+		Id:       refundId,
+		PlayerId: original.PlayerId,
+		RefundOf: original.Id,
+	}
+	for _, item := range original.Items {
+		item.Quantity = -item.Quantity
+		refund.Items = append(refund.Items, item)
+	}
+	return refund, t.Track(refund)
+}
+
+// Convert returns the USD total of items rounded to cents.
+func (t *PurchaseTracker) Convert(items []PurchaseItem) (float64, error) {
+	var total float64
+	for _, item := range items {
+		if !isCurrencyCode(item.Currency) {
+			return 0, fmt.Errorf("Invalid currency code %q for %s", item.Currency, item.SKU)
+		}
+		rate, err := t.rates.USDRate(item.Currency)
+		if err != nil {
+			return 0, err
+		}
+		total += float64(item.Quantity) * item.Price * rate
+	}
+	return math.Round(total*100) / 100, nil
+}
+
+// Reconcile compares the USD recorded in the ledger for a player with its
+// AmountSpent, returning the ledger total and AmountSpent minus that total.
+func (t *PurchaseTracker) Reconcile(player *Player) (total, diff float64, err error) {
+	if t.ledger == nil {
+		return 0, 0, errors.New("Purchase tracker has no ledger")
+	}
+	purchases, err := t.ledger.Purchases(player.Id)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, p := range purchases {
+		total += p.USD
+	}
+	total = math.Round(total*100) / 100
+	return total, math.Round((player.AmountSpent-total)*100) / 100, nil
+}
+
+func isCurrencyCode(str string) bool {
+	if len(str) != 3 {
+		return false
+	}
+	for _, r := range str {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
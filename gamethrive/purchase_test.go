@@ -0,0 +1,58 @@
+package gamethrive
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestPurchaseTracker(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	var amounts []float64
+	mux.HandleFunc("/players/p1/on_purchase", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Amount float64 `json:"amount"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		amounts = append(amounts, body.Amount)
+	})
+	rates := StaticRates{"USD": 1, "EUR": 1.5}
+	ledger := NewFileLedger(filepath.Join(t.TempDir(), "ledger.jsonl"))
+	tracker := NewPurchaseTracker(client, rates, ledger)
+
+	purchase := &Purchase{
+		Id:       "order-1",
+		PlayerId: "p1",
+		Items: []PurchaseItem{
+			{SKU: "gems", Quantity: 2, Price: 4.99, Currency: "EUR"},
+			{SKU: "skin", Quantity: 1, Price: 1.99, Currency: "USD"},
+		},
+	}
+	if err := tracker.Track(purchase); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tracker.Refund(&Purchase{Id: "order-1", PlayerId: "p1", Items: purchase.Items[1:]}, "refund-1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(amounts) != 2 || amounts[0] != 16.96 || amounts[1] != -1.99 {
+		t.Errorf("Sent amounts = %v, want [16.96 -1.99]", amounts)
+	}
+	total, diff, err := tracker.Reconcile(&Player{Id: "p1", AmountSpent: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 14.97 || diff != 5.03 {
+		t.Errorf("Reconcile = %v, %v, want 14.97, 5.03", total, diff)
+	}
+}
+
+func TestPurchaseTracker_invalidCurrency(t *testing.T) {
+	tracker := NewPurchaseTracker(NewClient(nil), StaticRates{"USD": 1}, nil)
+	for _, currency := range []string{"usd", "EURO", "GBP"} {
+		if _, err := tracker.Convert([]PurchaseItem{{SKU: "a", Quantity: 1, Price: 1, Currency: currency}}); err == nil {
+			t.Errorf("Convert with currency %q expected error", currency)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package gamethrive
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SendTimeLayout is the date format GameThrive expects for send_after.
+const SendTimeLayout = "Mon Jan 02 2006 15:04:05 GMT-0700"
+
+var ErrPastSendTime = errors.New("Send time is in the past")
+
+// SendTime is a delivery time for scheduled notifications. It is encoded
+// using SendTimeLayout instead of RFC3339.
+type SendTime struct {
+	time.Time
+}
+
+// NewSendTime returns a SendTime for t, failing if t is already past.
+func NewSendTime(t time.Time) (*SendTime, error) {
+	return newSendTime(t, time.Now())
+}
+
+// ParseSendTime parses absolute dates (SendTimeLayout, RFC3339 or
+// "2006-01-02 15:04") and relative ones such as "in 2h", "in 3d",
+// "today 18:00" or "tomorrow 18:00 Europe/Madrid". Times without an offset
+// or location name are taken as local time.
+func ParseSendTime(str string) (*SendTime, error) {
+	return parseSendTime(str, time.Now())
+}
+
+func (t SendTime) String() string {
+	return t.Format(SendTimeLayout)
+}
+
+func (t SendTime) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + t.String() + `"`), nil
+}
+
+func (t *SendTime) UnmarshalJSON(data []byte) error {
+	str := strings.Trim(string(data), `"`)
+	for _, layout := range absoluteLayouts {
+		if tt, err := time.Parse(layout, str); err == nil {
+			t.Time = tt
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid send time %q", str)
+}
+
+var absoluteLayouts = []string{
+	SendTimeLayout,
+	time.RFC3339,
+	"Mon Jan 02 2006 15:04:05 MST-0700",
+}
+
+func newSendTime(t, now time.Time) (*SendTime, error) {
+	if !t.After(now) {
+		return nil, ErrPastSendTime
+	}
+	return &SendTime{t}, nil
+}
+
+func parseSendTime(str string, now time.Time) (*SendTime, error) {
+	str = strings.TrimSpace(str)
+	for _, layout := range absoluteLayouts {
+		if t, err := time.Parse(layout, str); err == nil {
+			return newSendTime(t, now)
+		}
+	}
+	fields := strings.Fields(str)
+	if len(fields) == 0 {
+		return nil, errors.New("Send time is required")
+	}
+	switch strings.ToLower(fields[0]) {
+	case "in":
+		if len(fields) != 2 {
+			break
+		}
+		d, err := parseDuration(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		return newSendTime(now.Add(d), now)
+	case "today", "tomorrow":
+		if len(fields) < 2 || len(fields) > 3 {
+			break
+		}
+		loc, err := parseLocation(fields[2:], now.Location())
+		if err != nil {
+			return nil, err
+		}
+		clock, err := time.Parse("15:04", fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid time of day %q", fields[1])
+		}
+		day := now.In(loc)
+		if strings.ToLower(fields[0]) == "tomorrow" {
+			day = day.AddDate(0, 0, 1)
+		}
+		t := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+		return newSendTime(t, now)
+	default:
+		if len(fields) < 2 || len(fields) > 3 {
+			break
+		}
+		loc, err := parseLocation(fields[2:], now.Location())
+		if err != nil {
+			return nil, err
+		}
+		t, err := time.ParseInLocation("2006-01-02 15:04", fields[0]+" "+fields[1], loc)
+		if err != nil {
+			break
+		}
+		return newSendTime(t, now)
+	}
+	return nil, fmt.Errorf("Invalid send time %q", str)
+}
+
+// parseDuration extends time.ParseDuration with a "d" (days) unit.
+func parseDuration(str string) (time.Duration, error) {
+	if strings.HasSuffix(str, "d") {
+		var days int
+		if _, err := fmt.Sscanf(str, "%dd", &days); err != nil {
+			return 0, fmt.Errorf("Invalid duration %q", str)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid duration %q", str)
+	}
+	return d, nil
+}
+
+func parseLocation(fields []string, def *time.Location) (*time.Location, error) {
+	if len(fields) == 0 {
+		return def, nil
+	}
+	return time.LoadLocation(fields[0])
+}
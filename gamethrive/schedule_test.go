@@ -0,0 +1,69 @@
+package gamethrive
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseSendTime(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skip("Europe/Madrid time zone not available")
+	}
+	now := time.Date(2015, 9, 24, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"in 2h", now.Add(2 * time.Hour)},
+		{"in 3d", now.AddDate(0, 0, 3)},
+		{"today 18:00", time.Date(2015, 9, 24, 18, 0, 0, 0, time.UTC)},
+		{"tomorrow 18:00 Europe/Madrid", time.Date(2015, 9, 25, 18, 0, 0, 0, madrid)},
+		{"2015-10-01 09:30", time.Date(2015, 10, 1, 9, 30, 0, 0, time.UTC)},
+		{"Thu Sep 24 2015 14:00:00 GMT-0700", time.Date(2015, 9, 24, 21, 0, 0, 0, time.UTC)},
+		{"2015-09-24T14:00:00Z", time.Date(2015, 9, 24, 14, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		got, err := parseSendTime(test.in, now)
+		if err != nil {
+			t.Errorf("parseSendTime(%q) error = %v", test.in, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("parseSendTime(%q) = %v, want %v", test.in, got.Time, test.want)
+		}
+	}
+}
+
+func TestParseSendTime_invalid(t *testing.T) {
+	now := time.Date(2015, 9, 24, 12, 0, 0, 0, time.UTC)
+	for _, in := range []string{"", "in", "in soon", "today 25:00", "tomorrow 18:00 Nowhere/City", "next week"} {
+		if _, err := parseSendTime(in, now); err == nil {
+			t.Errorf("parseSendTime(%q) expected error", in)
+		}
+	}
+	for _, in := range []string{"today 09:00", "2015-09-23 10:00", "in -1h"} {
+		if _, err := parseSendTime(in, now); err != ErrPastSendTime {
+			t.Errorf("parseSendTime(%q) error = %v, want %v", in, err, ErrPastSendTime)
+		}
+	}
+}
+
+func TestSendTime_JSON(t *testing.T) {
+	in := SendTime{time.Date(2015, 9, 24, 14, 0, 0, 0, time.FixedZone("PDT", -7*3600))}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"Thu Sep 24 2015 14:00:00 GMT-0700"`; string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+	var out SendTime
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.Equal(in.Time) {
+		t.Errorf("Unmarshal = %v, want %v", out.Time, in.Time)
+	}
+}
@@ -0,0 +1,257 @@
+package gamethrive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CatchUpPolicy tells the scheduler what to do with runs that were missed
+// while it was not running.
+type CatchUpPolicy string
+
+const (
+	SkipMissed CatchUpPolicy = "skip"
+	RunOnce    CatchUpPolicy = "once"
+	RunAll     CatchUpPolicy = "all"
+)
+
+// Campaign is a notification template delivered on a recurring schedule.
+type Campaign struct {
+	Name     string        `json:"name"`
+	Spec     string        `json:"spec"`
+	Location string        `json:"location,omitempty"`
+	CatchUp  CatchUpPolicy `json:"catch_up,omitempty"`
+	Auth     string        `json:"auth,omitempty"`
+	// Template names a notification of a CampaignFile that Notification
+	// extends.
+	Template     string       `json:"template,omitempty"`
+	Notification Notification `json:"notification"`
+
+	cron *CronSpec
+}
+
+func (c *Campaign) schedule() (*CronSpec, error) {
+	var loc *time.Location
+	if len(c.Location) > 0 {
+		l, err := time.LoadLocation(c.Location)
+		if err != nil {
+			return nil, err
+		}
+		loc = l
+	}
+	return ParseCron(c.Spec, loc)
+}
+
+// CampaignState is the persisted progress of a campaign.
+type CampaignState struct {
+	NextRun        time.Time `json:"next_run"`
+	LastRun        time.Time `json:"last_run,omitempty"`
+	LastId         string    `json:"last_id,omitempty"`
+	LastRecipients int       `json:"last_recipients,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+}
+
+// Scheduler fires campaigns through Notifications.New, keeping the next run
+// of every campaign in a json file so it survives restarts.
+type Scheduler struct {
+	c    *Client
+	path string
+
+	// Interval between checks for due campaigns, defaults to one minute.
+	Interval time.Duration
+	// OnRun, when set, is called after each delivery attempt. Runs stopped
+	// by the client DedupGuard are reported as delivered, with the id of
+	// the original notification.
+	OnRun func(campaign *Campaign, scheduled time.Time, notification *Notification, recipients int, err error)
+
+	mu        sync.Mutex
+	campaigns map[string]*Campaign
+	state     map[string]*CampaignState
+	lastTick  time.Time
+}
+
+// NewScheduler creates a scheduler persisting its state at statePath. An
+// empty path keeps the state in memory only.
+func NewScheduler(client *Client, statePath string) (*Scheduler, error) {
+	s := &Scheduler{
+		c:         client,
+		path:      statePath,
+		Interval:  time.Minute,
+		campaigns: map[string]*Campaign{},
+		state:     map[string]*CampaignState{},
+	}
+	if len(statePath) <= 0 {
+		return s, nil
+	}
+	data, err := ioutil.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.state); err != nil {
+		return nil, fmt.Errorf("Invalid scheduler state %s: %s", statePath, err.Error())
+	}
+	return s, nil
+}
+
+// Add registers a campaign. If the campaign has no saved state its first
+// run is the next activation after now.
+func (s *Scheduler) Add(campaign *Campaign) error {
+	if len(campaign.Name) <= 0 {
+		return errors.New("Campaign name is required")
+	}
+	cron, err := campaign.schedule()
+	if err != nil {
+		return err
+	}
+	campaign.cron = cron
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.campaigns[campaign.Name]; ok {
+		return fmt.Errorf("Campaign %q already exists", campaign.Name)
+	}
+	s.campaigns[campaign.Name] = campaign
+	if _, ok := s.state[campaign.Name]; !ok {
+		s.state[campaign.Name] = &CampaignState{NextRun: cron.Next(time.Now())}
+	}
+	return s.save()
+}
+
+// Remove unregisters a campaign and forgets its state.
+func (s *Scheduler) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.campaigns, name)
+	delete(s.state, name)
+	return s.save()
+}
+
+// State returns a copy of the state of a campaign.
+func (s *Scheduler) State(name string) (CampaignState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.state[name]
+	if !ok {
+		return CampaignState{}, false
+	}
+	return *st, true
+}
+
+// Run checks for due campaigns every Interval until stop is closed.
+func (s *Scheduler) Run(stop <-chan struct{}) error {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Tick(time.Now()); err != nil {
+			return err
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick delivers every campaign due at now, applying its catch up policy
+// to runs missed before the latest one. Runs due before the previous tick,
+// or more than Interval before the first one, count as missed. Campaigns
+// are delivered without holding the scheduler lock, so OnRun may call its
+// methods.
+func (s *Scheduler) Tick(now time.Time) error {
+	s.mu.Lock()
+	since := s.lastTick
+	if since.IsZero() {
+		since = now.Add(-s.Interval)
+	}
+	s.lastTick = now
+	names := make([]string, 0, len(s.campaigns))
+	for name := range s.campaigns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var runs []scheduledRun
+	for _, name := range names {
+		campaign, st := s.campaigns[name], s.state[name]
+		var due []time.Time
+		for t := st.NextRun; !t.IsZero() && !t.After(now); t = campaign.cron.Next(t) {
+			due = append(due, t)
+		}
+		if len(due) <= 0 {
+			continue
+		}
+		switch campaign.CatchUp {
+		case RunAll:
+		case RunOnce:
+			due = due[len(due)-1:]
+		default:
+			if due[len(due)-1].Before(since) {
+				due = nil
+			} else {
+				due = due[len(due)-1:]
+			}
+		}
+		for _, t := range due {
+			runs = append(runs, scheduledRun{campaign, t})
+		}
+		st.NextRun = campaign.cron.Next(now)
+	}
+	err := s.save()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, run := range runs {
+		s.fire(run.campaign, run.scheduled, now)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save()
+}
+
+type scheduledRun struct {
+	campaign  *Campaign
+	scheduled time.Time
+}
+
+func (s *Scheduler) fire(campaign *Campaign, scheduled, now time.Time) {
+	notification := campaign.Notification
+	notification.SendAfter = nil
+	recipients, err := s.c.Notifications.New(&notification, campaign.Auth)
+	if err == ErrDuplicateNotification {
+		err = nil
+	}
+	s.mu.Lock()
+	if st, ok := s.state[campaign.Name]; ok {
+		st.LastRun = now
+		st.LastId = notification.Id
+		st.LastRecipients = recipients
+		st.LastError = ""
+		if err != nil {
+			st.LastError = err.Error()
+		}
+	}
+	s.mu.Unlock()
+	if s.OnRun != nil {
+		s.OnRun(campaign, scheduled, &notification, recipients, err)
+	}
+}
+
+func (s *Scheduler) save() error {
+	if len(s.path) <= 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data)
+}
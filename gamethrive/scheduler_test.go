@@ -0,0 +1,116 @@
+package gamethrive
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScheduler_Tick(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	sent := 0
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		fmt.Fprintf(w, `{"id":"n%d","recipients":3}`, sent)
+	})
+	path := filepath.Join(t.TempDir(), "state.json")
+	tests := []struct {
+		policy CatchUpPolicy
+		want   int
+	}{
+		{SkipMissed, 0},
+		{RunOnce, 1},
+		{RunAll, 3},
+	}
+	for _, test := range tests {
+		sent = 0
+		s, err := NewScheduler(client, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		campaign := &Campaign{Name: string(test.policy), Spec: "0 18 * * *", Location: "UTC", CatchUp: test.policy}
+		if err := s.Add(campaign); err != nil {
+			t.Fatal(err)
+		}
+		s.state[campaign.Name].NextRun = time.Date(2015, 9, 22, 18, 0, 0, 0, time.UTC)
+		now := time.Date(2015, 9, 24, 20, 0, 0, 0, time.UTC)
+		if err := s.Tick(now); err != nil {
+			t.Fatal(err)
+		}
+		if sent != test.want {
+			t.Errorf("Tick with %q policy sent %d notifications, want %d", test.policy, sent, test.want)
+		}
+		reloaded, err := NewScheduler(client, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		st, _ := reloaded.State(campaign.Name)
+		if want := time.Date(2015, 9, 25, 18, 0, 0, 0, time.UTC); !st.NextRun.Equal(want) {
+			t.Errorf("Persisted NextRun = %v, want %v", st.NextRun, want)
+		}
+	}
+}
+
+func TestScheduler_Tick_late(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"n1","recipients":3}`)
+	})
+	s, _ := NewScheduler(client, "")
+	campaign := &Campaign{Name: "daily", Spec: "0 18 * * *", Location: "UTC"}
+	if err := s.Add(campaign); err != nil {
+		t.Fatal(err)
+	}
+	var runs []string
+	s.OnRun = func(c *Campaign, scheduled time.Time, n *Notification, recipients int, err error) {
+		st, _ := s.State(c.Name)
+		runs = append(runs, st.LastId)
+	}
+	s.state[campaign.Name].NextRun = time.Date(2015, 9, 24, 18, 0, 0, 0, time.UTC)
+	if err := s.Tick(time.Date(2015, 9, 24, 17, 59, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	// The next tick comes five minutes late, the run is not a missed one.
+	if err := s.Tick(time.Date(2015, 9, 24, 18, 5, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0] != "n1" {
+		t.Errorf("Late tick runs = %v, want [n1]", runs)
+	}
+}
+
+func TestScheduler_Tick_duplicate(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	sent := 0
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		fmt.Fprintf(w, `{"id":"n%d","recipients":3}`, sent)
+	})
+	client.Dedup = NewDedupGuard(nil, time.Hour)
+	s, _ := NewScheduler(client, "")
+	campaign := &Campaign{Name: "daily", Spec: "0 18 * * *", Location: "UTC", Notification: Notification{AppId: "app"}}
+	if err := s.Add(campaign); err != nil {
+		t.Fatal(err)
+	}
+	n := campaign.Notification
+	if _, err := client.Notifications.New(&n, ""); err != nil {
+		t.Fatal(err)
+	}
+	var runErr error
+	s.OnRun = func(c *Campaign, scheduled time.Time, n *Notification, recipients int, err error) {
+		runErr = err
+	}
+	s.state[campaign.Name].NextRun = time.Date(2015, 9, 24, 18, 0, 0, 0, time.UTC)
+	if err := s.Tick(time.Date(2015, 9, 24, 18, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	st, _ := s.State(campaign.Name)
+	if sent != 1 || runErr != nil || st.LastError != "" || st.LastId != "n1" {
+		t.Errorf("Duplicate run sent %d, error %v, state %+v", sent, runErr, st)
+	}
+}
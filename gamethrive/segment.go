@@ -0,0 +1,113 @@
+package gamethrive
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type SegmentsService struct {
+	c *Client
+}
+
+type Segment struct {
+	Id      string   `json:"id,omitempty"`
+	AppId   string   `json:"-"`
+	Name    string   `json:"name"`
+	Filters []Filter `json:"filters,omitempty"`
+}
+
+// builtinSegments always exist in every application.
+var builtinSegments = []string{"All"}
+
+func (s *SegmentsService) List(appId string, auth string) ([]Segment, error) {
+	if len(appId) <= 0 {
+		return nil, errors.New("App id is required")
+	}
+	req, err := s.c.NewRequest("GET", fmt.Sprintf("apps/%s/segments", appId), nil)
+	if err != nil {
+		return nil, err
+	}
+	setAuth(req, auth)
+	var res struct {
+		Segments []Segment `json:"segments"`
+	}
+	_, err = s.c.Do(req, &res)
+	if err != nil {
+		return nil, err
+	}
+	for i := range res.Segments {
+		res.Segments[i].AppId = appId
+	}
+	return res.Segments, nil
+}
+
+func (s *SegmentsService) New(segment *Segment, auth string) error {
+	if len(segment.AppId) <= 0 {
+		return errors.New("App id is required")
+	}
+	if len(segment.Name) <= 0 {
+		return errors.New("Segment name is required")
+	}
+	req, err := s.c.NewRequest("POST", fmt.Sprintf("apps/%s/segments", segment.AppId), segment)
+	if err != nil {
+		return err
+	}
+	setAuth(req, auth)
+	var res struct {
+		Success bool   `json:"success"`
+		Id      string `json:"id"`
+	}
+	_, err = s.c.Do(req, &res)
+	if err != nil {
+		return err
+	}
+	segment.Id = res.Id
+	return nil
+}
+
+func (s *SegmentsService) Delete(appId, segmentId string, auth string) error {
+	if len(appId) <= 0 {
+		return errors.New("App id is required")
+	}
+	if len(segmentId) <= 0 {
+		return errors.New("Segment id is required")
+	}
+	req, err := s.c.NewRequest("DELETE", fmt.Sprintf("apps/%s/segments/%s", appId, segmentId), nil)
+	if err != nil {
+		return err
+	}
+	setAuth(req, auth)
+	_, err = s.c.Do(req, nil)
+	return err
+}
+
+// Check verifies that every segment included or excluded by notification
+// exists in its application.
+func (s *SegmentsService) Check(notification *Notification, auth string) error {
+	names := append(append([]string{}, notification.IncludedSegments...), notification.ExcludedSegments...)
+	if len(names) <= 0 {
+		return nil
+	}
+	segments, err := s.List(notification.AppId, auth)
+	if err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, name := range builtinSegments {
+		known[name] = true
+	}
+	for _, segment := range segments {
+		known[segment.Name] = true
+	}
+	var missing []string
+	for _, name := range names {
+		if !known[name] {
+			missing = append(missing, fmt.Sprintf("%q", name))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("Unknown segments: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
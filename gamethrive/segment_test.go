@@ -0,0 +1,89 @@
+package gamethrive
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestSegmentsService_List(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	mux.HandleFunc("/apps/app/segments", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			t.Errorf("Request method = %v, want GET", r.Method)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Basic key" {
+			t.Errorf("Authorization = %q, want %q", auth, "Basic key")
+		}
+		fmt.Fprint(w, `{"segments":[{"id":"s1","name":"Whales"},{"id":"s2","name":"Lapsed"}]}`)
+	})
+	segments, err := client.Segments.List("app", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Segment{{Id: "s1", AppId: "app", Name: "Whales"}, {Id: "s2", AppId: "app", Name: "Lapsed"}}
+	if !reflect.DeepEqual(segments, want) {
+		t.Errorf("List = %+v, want %+v", segments, want)
+	}
+	if _, err := client.Segments.List("", "key"); err == nil {
+		t.Error("List without app id expected error")
+	}
+}
+
+func TestSegmentsService_New(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	mux.HandleFunc("/apps/app/segments", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("Request method = %v, want POST", r.Method)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Basic key" {
+			t.Errorf("Authorization = %q, want %q", auth, "Basic key")
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["name"] != "Whales" || body["filters"] == nil {
+			t.Errorf("Request body = %v, want the segment name and filters", body)
+		}
+		fmt.Fprint(w, `{"success":true,"id":"s1"}`)
+	})
+	segment := &Segment{
+		AppId:   "app",
+		Name:    "Whales",
+		Filters: []Filter{{Field: "amount_spent", Relation: ">", Value: "100"}},
+	}
+	if err := client.Segments.New(segment, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if segment.Id != "s1" {
+		t.Errorf("Segment id = %q, want s1", segment.Id)
+	}
+	if err := client.Segments.New(&Segment{AppId: "app"}, "key"); err == nil {
+		t.Error("New without name expected error")
+	}
+}
+
+func TestSegmentsService_Delete(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	deleted := false
+	mux.HandleFunc("/apps/app/segments/s1", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" {
+			t.Errorf("Request method = %v, want DELETE", r.Method)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Basic key" {
+			t.Errorf("Authorization = %q, want %q", auth, "Basic key")
+		}
+		deleted = true
+		fmt.Fprint(w, `{"success":true}`)
+	})
+	if err := client.Segments.Delete("app", "s1", "key"); err != nil || !deleted {
+		t.Errorf("Delete = %v, deleted = %v", err, deleted)
+	}
+	if err := client.Segments.Delete("app", "", "key"); err == nil {
+		t.Error("Delete without segment id expected error")
+	}
+}
@@ -0,0 +1,267 @@
+package gamethrive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PlaySession is the wall-clock accounting of a player between a Start and
+// a Stop.
+type PlaySession struct {
+	PlayerId string    `json:"player_id"`
+	Started  time.Time `json:"started"`
+	// Resumed is when the current active period began, zero while paused.
+	Resumed time.Time `json:"resumed,omitempty"`
+	// Active is the active time accumulated before Resumed.
+	Active time.Duration `json:"active"`
+	// Reported is the active time already sent to GameThrive.
+	Reported time.Duration `json:"reported"`
+}
+
+func (s *PlaySession) active(now time.Time) time.Duration {
+	if s.Resumed.IsZero() {
+		return s.Active
+	}
+	return s.Active + now.Sub(s.Resumed)
+}
+
+// SessionTracker keeps the playtime of each player and reports it through
+// Players.Playtime: a Resume when a session starts or resumes, periodic
+// Pings with the time played since the last report and a Suspend with the
+// remaining seconds when it is paused or stopped.
+type SessionTracker struct {
+	c    *Client
+	path string
+
+	// PingInterval between playtime pings, defaults to one minute.
+	PingInterval time.Duration
+	// OnError, when set, is called with errors from background pings.
+	OnError func(playerId string, err error)
+
+	mu       sync.Mutex
+	sessions map[string]*PlaySession
+}
+
+// NewSessionTracker creates a tracker checkpointing its sessions at
+// checkpointPath, restoring the sessions found there. Sessions that were
+// active when the checkpoint was written keep counting from their last
+// resume. An empty path keeps the sessions in memory only.
+func NewSessionTracker(client *Client, checkpointPath string) (*SessionTracker, error) {
+	t := &SessionTracker{
+		c:            client,
+		path:         checkpointPath,
+		PingInterval: time.Minute,
+		sessions:     map[string]*PlaySession{},
+	}
+	if len(checkpointPath) <= 0 {
+		return t, nil
+	}
+	data, err := ioutil.ReadFile(checkpointPath)
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &t.sessions); err != nil {
+		return nil, fmt.Errorf("Invalid session checkpoint %s: %s", checkpointPath, err.Error())
+	}
+	return t, nil
+}
+
+// Start opens a new session for a player. Starting an already tracked
+// player stops the previous session first.
+func (t *SessionTracker) Start(playerId string, now time.Time) error {
+	if len(playerId) <= 0 {
+		return errors.New("Player id is required")
+	}
+	t.mu.Lock()
+	_, ok := t.sessions[playerId]
+	t.mu.Unlock()
+	if ok {
+		if err := t.Stop(playerId, now); err != nil {
+			return err
+		}
+	}
+	t.mu.Lock()
+	t.sessions[playerId] = &PlaySession{PlayerId: playerId, Started: now, Resumed: now}
+	err := t.checkpoint()
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return t.c.Players.Playtime(playerId, Resume, 0)
+}
+
+// Pause stops counting time for a player and reports the pending time with
+// a Suspend.
+func (t *SessionTracker) Pause(playerId string, now time.Time) error {
+	t.mu.Lock()
+	s, ok := t.sessions[playerId]
+	if !ok {
+		t.mu.Unlock()
+		return fmt.Errorf("No session for player %s", playerId)
+	}
+	if s.Resumed.IsZero() {
+		t.mu.Unlock()
+		return nil
+	}
+	s.Active = s.active(now)
+	s.Resumed = time.Time{}
+	claimed, err := t.claim(s, now)
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return t.report(playerId, Suspend, claimed)
+}
+
+// Resume continues counting time for a paused player.
+func (t *SessionTracker) Resume(playerId string, now time.Time) error {
+	t.mu.Lock()
+	s, ok := t.sessions[playerId]
+	if !ok {
+		t.mu.Unlock()
+		return fmt.Errorf("No session for player %s", playerId)
+	}
+	if !s.Resumed.IsZero() {
+		t.mu.Unlock()
+		return nil
+	}
+	s.Resumed = now
+	err := t.checkpoint()
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return t.c.Players.Playtime(playerId, Resume, 0)
+}
+
+// Stop closes the session of a player, sending a final Suspend with the
+// time not reported yet. The session is kept if the Suspend fails.
+func (t *SessionTracker) Stop(playerId string, now time.Time) error {
+	t.mu.Lock()
+	s, ok := t.sessions[playerId]
+	if !ok {
+		t.mu.Unlock()
+		return fmt.Errorf("No session for player %s", playerId)
+	}
+	claimed, err := t.claim(s, now)
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := t.report(playerId, Suspend, claimed); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sessions[playerId] == s {
+		delete(t.sessions, playerId)
+	}
+	return t.checkpoint()
+}
+
+// Session returns a copy of the current session of a player.
+func (t *SessionTracker) Session(playerId string) (PlaySession, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.sessions[playerId]
+	if !ok {
+		return PlaySession{}, false
+	}
+	return *s, true
+}
+
+// Ping reports the time played since the last report for every active
+// session.
+func (t *SessionTracker) Ping(now time.Time) {
+	t.mu.Lock()
+	ids := make([]string, 0, len(t.sessions))
+	for id, s := range t.sessions {
+		if !s.Resumed.IsZero() {
+			ids = append(ids, id)
+		}
+	}
+	t.mu.Unlock()
+	sort.Strings(ids)
+	for _, id := range ids {
+		t.mu.Lock()
+		var claimed time.Duration
+		var err error
+		if s, ok := t.sessions[id]; ok && !s.Resumed.IsZero() {
+			claimed, err = t.claim(s, now)
+		}
+		t.mu.Unlock()
+		if err == nil && claimed > 0 {
+			err = t.report(id, Ping, claimed)
+		}
+		if err != nil && t.OnError != nil {
+			t.OnError(id, err)
+		}
+	}
+}
+
+// Run pings every PingInterval until stop is closed.
+func (t *SessionTracker) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(t.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			t.Ping(now)
+		}
+	}
+}
+
+// claim marks the whole seconds played and not reported yet as reported
+// before they are sent, so concurrent reports never send them twice. The
+// remainder is left for the next report. It must be called holding t.mu.
+func (t *SessionTracker) claim(s *PlaySession, now time.Time) (time.Duration, error) {
+	claimed := time.Duration(seconds(s.active(now)-s.Reported)) * time.Second
+	s.Reported += claimed
+	if err := t.checkpoint(); err != nil {
+		s.Reported -= claimed
+		return 0, err
+	}
+	return claimed, nil
+}
+
+// report sends time claimed from the session of a player, giving it back
+// to the session if the call fails.
+func (t *SessionTracker) report(playerId string, state PlaytimeState, claimed time.Duration) error {
+	err := t.c.Players.Playtime(playerId, state, seconds(claimed))
+	if err == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s, ok := t.sessions[playerId]; ok {
+		s.Reported -= claimed
+		t.checkpoint()
+	}
+	return err
+}
+
+func (t *SessionTracker) checkpoint() error {
+	if len(t.path) <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(t.sessions)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(t.path, data)
+}
+
+func seconds(d time.Duration) int {
+	return int(d / time.Second)
+}
@@ -0,0 +1,146 @@
+package gamethrive
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type playtimeCall struct {
+	State PlaytimeState `json:"state"`
+	Time  int           `json:"active_time"`
+}
+
+func setupPlaytime(t *testing.T) (*Client, func() []playtimeCall, func(int), func()) {
+	server, mux, client := setup()
+	var mu sync.Mutex
+	var calls []playtimeCall
+	status := http.StatusOK
+	mux.HandleFunc("/players/p1/on_focus", func(w http.ResponseWriter, r *http.Request) {
+		var call playtimeCall
+		if err := json.NewDecoder(r.Body).Decode(&call); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		calls = append(calls, call)
+		fmt.Fprint(w, `{"success":true}`)
+	})
+	get := func() []playtimeCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]playtimeCall(nil), calls...)
+	}
+	fail := func(s int) {
+		mu.Lock()
+		status = s
+		mu.Unlock()
+	}
+	return client, get, fail, server.Close
+}
+
+func TestSessionTracker(t *testing.T) {
+	client, calls, _, done := setupPlaytime(t)
+	defer done()
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	tracker, err := NewSessionTracker(client, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2015, 9, 24, 12, 0, 0, 0, time.UTC)
+	steps := []func() error{
+		func() error { return tracker.Start("p1", start) },
+		func() error { tracker.Ping(start.Add(90 * time.Second)); return nil },
+		func() error { return tracker.Pause("p1", start.Add(100500*time.Millisecond)) },
+		func() error { return tracker.Resume("p1", start.Add(200*time.Second)) },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reloaded, err := NewSessionTracker(client, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, ok := reloaded.Session("p1")
+	if !ok || !s.Started.Equal(start) || s.Reported != 100*time.Second || s.Active != 100500*time.Millisecond {
+		t.Errorf("Reloaded session = %+v, want started at %v with 100s reported", s, start)
+	}
+	if err := reloaded.Stop("p1", start.Add(230*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reloaded.Session("p1"); ok {
+		t.Error("Session still tracked after Stop")
+	}
+
+	want := []playtimeCall{{Resume, 0}, {Ping, 90}, {Suspend, 10}, {Resume, 0}, {Suspend, 30}}
+	got := calls()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Playtime calls = %v, want %v", got, want)
+	}
+}
+
+func TestSessionTracker_Stop_failure(t *testing.T) {
+	client, calls, fail, done := setupPlaytime(t)
+	defer done()
+	tracker, _ := NewSessionTracker(client, "")
+	start := time.Date(2015, 9, 24, 12, 0, 0, 0, time.UTC)
+	if err := tracker.Start("p1", start); err != nil {
+		t.Fatal(err)
+	}
+	fail(http.StatusServiceUnavailable)
+	if err := tracker.Stop("p1", start.Add(time.Minute)); err == nil {
+		t.Fatal("Stop expected error")
+	}
+	if s, ok := tracker.Session("p1"); !ok || s.Reported != 0 {
+		t.Fatalf("Session after failed Stop = %+v, %v, want it kept unreported", s, ok)
+	}
+	fail(http.StatusOK)
+	if err := tracker.Stop("p1", start.Add(2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if got := calls(); len(got) != 2 || got[1] != (playtimeCall{Suspend, 120}) {
+		t.Errorf("Playtime calls = %v, want a Suspend of 120 seconds", got)
+	}
+}
+
+func TestSessionTracker_concurrent(t *testing.T) {
+	client, calls, _, done := setupPlaytime(t)
+	defer done()
+	tracker, _ := NewSessionTracker(client, "")
+	start := time.Date(2015, 9, 24, 12, 0, 0, 0, time.UTC)
+	if err := tracker.Start("p1", start); err != nil {
+		t.Fatal(err)
+	}
+	now := start.Add(time.Minute)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			tracker.Ping(now)
+		}()
+		go func() {
+			defer wg.Done()
+			tracker.Pause("p1", now)
+		}()
+	}
+	wg.Wait()
+	total := 0
+	for _, call := range calls() {
+		total += call.Time
+	}
+	if total != 60 {
+		t.Errorf("Reported %d seconds, want 60", total)
+	}
+}
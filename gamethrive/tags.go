@@ -0,0 +1,255 @@
+package gamethrive
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type TagType int
+
+const (
+	TagString TagType = iota
+	TagInt
+	TagFloat
+	TagBool
+	TagTime
+)
+
+func (t TagType) String() string {
+	switch t {
+	case TagInt:
+		return "int"
+	case TagFloat:
+		return "float"
+	case TagBool:
+		return "bool"
+	case TagTime:
+		return "time"
+	default:
+		return "string"
+	}
+}
+
+// TagSchema declares the type of each known tag. Times are stored as
+// unixtime so they can be compared by segment filters.
+type TagSchema map[string]TagType
+
+// Check validates that value is a valid encoding for the type of key.
+// Unknown keys are rejected, empty values (deletions) are always valid.
+func (s TagSchema) Check(key, value string) error {
+	typ, ok := s[key]
+	if !ok {
+		return fmt.Errorf("Unknown tag %q", key)
+	}
+	if len(value) <= 0 {
+		return nil
+	}
+	var err error
+	switch typ {
+	case TagInt, TagTime:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case TagFloat:
+		_, err = strconv.ParseFloat(value, 64)
+	case TagBool:
+		_, err = strconv.ParseBool(value)
+	}
+	if err != nil {
+		return fmt.Errorf("Tag %q must be %s, got %q", key, typ, value)
+	}
+	return nil
+}
+
+// Validate checks every tag against the schema.
+func (s TagSchema) Validate(tags map[string]string) error {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := s.Check(k, tags[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TagChanges is a set of tag updates and deletions for a player.
+type TagChanges struct {
+	Set    map[string]string
+	Delete []string
+}
+
+// DiffTags returns the changes needed to turn old into updated.
+func DiffTags(old, updated map[string]string) *TagChanges {
+	c := NewTagChanges()
+	for k, v := range updated {
+		if ov, ok := old[k]; !ok || ov != v {
+			c.Set[k] = v
+		}
+	}
+	for k := range old {
+		if _, ok := updated[k]; !ok {
+			c.Delete = append(c.Delete, k)
+		}
+	}
+	sort.Strings(c.Delete)
+	return c
+}
+
+// NewTagChanges returns an empty set of changes.
+func NewTagChanges() *TagChanges {
+	return &TagChanges{Set: map[string]string{}}
+}
+
+func (c *TagChanges) SetString(key, value string) {
+	c.set(key, value)
+}
+
+func (c *TagChanges) SetInt(key string, value int64) {
+	c.set(key, strconv.FormatInt(value, 10))
+}
+
+func (c *TagChanges) SetFloat(key string, value float64) {
+	c.set(key, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+func (c *TagChanges) SetBool(key string, value bool) {
+	c.set(key, strconv.FormatBool(value))
+}
+
+func (c *TagChanges) SetTime(key string, value time.Time) {
+	c.set(key, strconv.FormatInt(value.Unix(), 10))
+}
+
+// DeleteTags marks keys for removal.
+func (c *TagChanges) DeleteTags(keys ...string) {
+	for _, k := range keys {
+		delete(c.Set, k)
+		c.Delete = append(c.Delete, k)
+	}
+}
+
+// Empty reports whether there is nothing to send.
+func (c *TagChanges) Empty() bool {
+	return len(c.Set) <= 0 && len(c.Delete) <= 0
+}
+
+// Tags returns the changes in the wire format, where deleted tags are sent
+// as blank strings.
+func (c *TagChanges) Tags() map[string]string {
+	tags := map[string]string{}
+	for k, v := range c.Set {
+		tags[k] = v
+	}
+	for _, k := range c.Delete {
+		tags[k] = ""
+	}
+	return tags
+}
+
+func (c *TagChanges) set(key, value string) {
+	if c.Set == nil {
+		c.Set = map[string]string{}
+	}
+	c.Set[key] = value
+	for i, k := range c.Delete {
+		if k == key {
+			c.Delete = append(c.Delete[:i], c.Delete[i+1:]...)
+			break
+		}
+	}
+}
+
+// Apply updates tags with the changes.
+func (c *TagChanges) Apply(tags map[string]string) map[string]string {
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	for k, v := range c.Set {
+		tags[k] = v
+	}
+	for _, k := range c.Delete {
+		delete(tags, k)
+	}
+	return tags
+}
+
+func (p *Player) StringTag(key string) (string, bool) {
+	v, ok := p.Tags[key]
+	return v, ok
+}
+
+func (p *Player) IntTag(key string) (int64, bool, error) {
+	v, ok := p.Tags[key]
+	if !ok {
+		return 0, false, nil
+	}
+	i, err := strconv.ParseInt(v, 10, 64)
+	return i, true, err
+}
+
+func (p *Player) FloatTag(key string) (float64, bool, error) {
+	v, ok := p.Tags[key]
+	if !ok {
+		return 0, false, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	return f, true, err
+}
+
+func (p *Player) BoolTag(key string) (bool, bool, error) {
+	v, ok := p.Tags[key]
+	if !ok {
+		return false, false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	return b, true, err
+}
+
+func (p *Player) TimeTag(key string) (time.Time, bool, error) {
+	i, ok, err := p.IntTag(key)
+	if !ok || err != nil {
+		return time.Time{}, ok, err
+	}
+	return time.Unix(i, 0), true, nil
+}
+
+// UpdateTags sends only the given tag changes for a player. When the client
+// has a TagSchema the changes are validated against it first.
+func (s *PlayersService) UpdateTags(playerId string, changes *TagChanges) error {
+	if len(playerId) <= 0 {
+		return errors.New("Player id is required")
+	}
+	if changes.Empty() {
+		return nil
+	}
+	tags := changes.Tags()
+	if s.c.TagSchema != nil {
+		if err := s.c.TagSchema.Validate(tags); err != nil {
+			return err
+		}
+	}
+	urlStr := fmt.Sprintf("players/%s", playerId)
+	body := struct {
+		Tags map[string]string `json:"tags"`
+	}{
+		Tags: tags,
+	}
+	req, err := s.c.NewRequest("PUT", urlStr, body)
+	if err != nil {
+		return err
+	}
+	_, err = s.c.Do(req, nil)
+	return err
+}
+
+// DeleteTags removes tags from a player.
+func (s *PlayersService) DeleteTags(playerId string, keys ...string) error {
+	changes := NewTagChanges()
+	changes.DeleteTags(keys...)
+	return s.UpdateTags(playerId, changes)
+}
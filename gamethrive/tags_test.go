@@ -0,0 +1,59 @@
+package gamethrive
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffTags(t *testing.T) {
+	old := map[string]string{"level": "1", "guild": "red", "vip": "true"}
+	updated := map[string]string{"level": "2", "vip": "true", "coins": "10"}
+	c := DiffTags(old, updated)
+	if want := map[string]string{"level": "2", "coins": "10"}; !reflect.DeepEqual(c.Set, want) {
+		t.Errorf("DiffTags Set = %v, want %v", c.Set, want)
+	}
+	if want := []string{"guild"}; !reflect.DeepEqual(c.Delete, want) {
+		t.Errorf("DiffTags Delete = %v, want %v", c.Delete, want)
+	}
+	if got := c.Apply(old); !reflect.DeepEqual(got, updated) {
+		t.Errorf("Apply = %v, want %v", got, updated)
+	}
+}
+
+func TestPlayersService_UpdateTags(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	client.TagSchema = TagSchema{"level": TagInt, "last_win": TagTime, "guild": TagString}
+	var body map[string]map[string]string
+	mux.HandleFunc("/players/p1", func(w http.ResponseWriter, r *http.Request) {
+		if m := "PUT"; r.Method != m {
+			t.Errorf("Request method = %v, want %v", r.Method, m)
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+	})
+	c := NewTagChanges()
+	c.SetInt("level", 7)
+	c.SetTime("last_win", time.Unix(1443139200, 0))
+	c.DeleteTags("guild")
+	if err := client.Players.UpdateTags("p1", c); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"level": "7", "last_win": "1443139200", "guild": ""}
+	if !reflect.DeepEqual(body["tags"], want) {
+		t.Errorf("Request tags = %v, want %v", body["tags"], want)
+	}
+
+	c = NewTagChanges()
+	c.SetString("level", "seven")
+	if err := client.Players.UpdateTags("p1", c); err == nil {
+		t.Error("Expected schema error for non int level")
+	}
+	c = NewTagChanges()
+	c.SetBool("unknown", true)
+	if err := client.Players.UpdateTags("p1", c); err == nil {
+		t.Error("Expected schema error for unknown tag")
+	}
+}
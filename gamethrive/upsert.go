@@ -0,0 +1,74 @@
+package gamethrive
+
+import (
+	"errors"
+)
+
+// UpsertResult tells which path Players.Upsert took.
+type UpsertResult string
+
+const (
+	PlayerCreated UpsertResult = "created"
+	PlayerUpdated UpsertResult = "updated"
+)
+
+// Upsert updates the player registered for the same device (matching
+// Identifier or AdvertisingId) or creates it if there is none. The device is
+// looked up in the client IdentityStore first and then, when auth is not
+// empty, by listing the players of the application.
+func (s *PlayersService) Upsert(player *Player, auth string) (UpsertResult, error) {
+	if len(player.Id) > 0 {
+		return PlayerUpdated, s.update(player)
+	}
+	if len(player.Identifier) <= 0 && len(player.AdvertisingId) <= 0 {
+		return "", errors.New("Player identifier or ad_id is required")
+	}
+	if store := s.c.Identities; store != nil {
+		id, ok, err := lookupDevice(store, player)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			player.Id = id
+			return PlayerUpdated, s.update(player)
+		}
+	}
+	if len(auth) > 0 {
+		id, err := s.findDevice(player, auth)
+		if err != nil {
+			return "", err
+		}
+		if len(id) > 0 {
+			player.Id = id
+			return PlayerUpdated, s.update(player)
+		}
+	}
+	return PlayerCreated, s.New(player)
+}
+
+// findDevice returns the id of the player with the same device as player,
+// or an empty string if there is none.
+func (s *PlayersService) findDevice(player *Player, auth string) (string, error) {
+	players, err := s.All(player.AppId, auth)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range players {
+		if p.DeviceType != player.DeviceType {
+			continue
+		}
+		if len(player.Identifier) > 0 && p.Identifier == player.Identifier ||
+			len(player.AdvertisingId) > 0 && p.AdvertisingId == player.AdvertisingId {
+			return p.Id, nil
+		}
+	}
+	return "", nil
+}
+
+// update sends player and records it in the client IdentityStore.
+func (s *PlayersService) update(player *Player) error {
+	if err := s.Update(player); err != nil {
+		return err
+	}
+	return s.remember(player)
+}
@@ -0,0 +1,70 @@
+package gamethrive
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestPlayersService_Upsert(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	client.Identities = NewMemoryIdentityStore()
+	created, updated := 0, map[string]int{}
+	mux.HandleFunc("/players", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			created++
+			fmt.Fprintf(w, `{"success":true,"id":"new%d"}`, created)
+		case "GET":
+			if auth := r.Header.Get("Authorization"); auth != "Basic key" {
+				t.Errorf("Authorization = %q, want %q", auth, "Basic key")
+			}
+			fmt.Fprint(w, `{"total_count":1,"offset":0,"limit":300,"players":[
+				{"id":"remote","device_type":1,"identifier":"other","ad_id":"ad-1"}]}`)
+		}
+	})
+	mux.HandleFunc("/players/", func(w http.ResponseWriter, r *http.Request) {
+		updated[r.URL.Path[len("/players/"):]]++
+	})
+
+	first := &Player{AppId: "app", DeviceType: Android, Identifier: "tok", ExternalUserId: "u1"}
+	if res, err := client.Players.Upsert(first, ""); err != nil || res != PlayerCreated || first.Id != "new1" {
+		t.Errorf("Upsert new device = %v, %v, id %q", res, err, first.Id)
+	}
+	again := &Player{AppId: "app", DeviceType: Android, Identifier: "tok"}
+	if res, err := client.Players.Upsert(again, ""); err != nil || res != PlayerUpdated || again.Id != "new1" {
+		t.Errorf("Upsert cached device = %v, %v, id %q", res, err, again.Id)
+	}
+	remote := &Player{AppId: "app", DeviceType: Android, Identifier: "rotated", AdvertisingId: "ad-1"}
+	if res, err := client.Players.Upsert(remote, "key"); err != nil || res != PlayerUpdated || remote.Id != "remote" {
+		t.Errorf("Upsert remote device = %v, %v, id %q", res, err, remote.Id)
+	}
+	if created != 1 || updated["new1"] != 1 || updated["remote"] != 1 {
+		t.Errorf("Requests: %d created, %v updated", created, updated)
+	}
+	if ids, _ := client.Identities.PlayerIds("u1"); len(ids) != 1 || ids[0] != "new1" {
+		t.Errorf("PlayerIds(u1) = %v, want [new1]", ids)
+	}
+}
+
+func TestPlayersService_Upsert_shortPages(t *testing.T) {
+	server, mux, client := setup()
+	defer server.Close()
+	mux.HandleFunc("/players", func(w http.ResponseWriter, r *http.Request) {
+		switch offset := r.URL.Query().Get("offset"); offset {
+		case "0":
+			fmt.Fprint(w, `{"total_count":3,"players":[{"id":"a","identifier":"ta"},{"id":"b","identifier":"tb"}]}`)
+		case "2":
+			fmt.Fprint(w, `{"total_count":3,"players":[{"id":"c","identifier":"tc"}]}`)
+		default:
+			t.Errorf("Unexpected offset %s", offset)
+			fmt.Fprint(w, `{"total_count":3,"players":[]}`)
+		}
+	})
+	mux.HandleFunc("/players/c", func(w http.ResponseWriter, r *http.Request) {})
+	player := &Player{AppId: "app", Identifier: "tc"}
+	if res, err := client.Players.Upsert(player, "key"); err != nil || res != PlayerUpdated || player.Id != "c" {
+		t.Errorf("Upsert of a device on a short page = %v, %v, id %q", res, err, player.Id)
+	}
+}
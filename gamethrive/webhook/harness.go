@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SampleEvents returns one event of each type for a notification and
+// player, to exercise a receiver locally.
+func SampleEvents(appId, notificationId, playerId string) []interface{} {
+	now := time.Now().Unix()
+	event := func(t EventType) Event {
+		id := make([]byte, 8)
+		rand.Read(id)
+		return Event{
+			Id:             hex.EncodeToString(id),
+			Type:           t,
+			AppId:          appId,
+			NotificationId: notificationId,
+			PlayerId:       playerId,
+			Timestamp:      now,
+		}
+	}
+	return []interface{}{
+		&DisplayedEvent{event(Displayed)},
+		&ClickedEvent{Event: event(Clicked), ActionId: "sample-action", LaunchURL: "https://example.com/"},
+		&DismissedEvent{event(Dismissed)},
+	}
+}
+
+// Post sends events to url as a single signed request, the way GameThrive
+// would. A nil client uses http.DefaultClient and an empty secret sends an
+// unsigned request.
+func Post(client *http.Client, url, secret string, events ...interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	var payload interface{} = events
+	if len(events) == 1 {
+		payload = events[0]
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(secret, body))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("Webhook rejected with status %s", resp.Status)
+	}
+	return nil
+}
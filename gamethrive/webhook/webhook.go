@@ -0,0 +1,347 @@
+// Package webhook receives the notification events GameThrive posts back
+// to an application: displayed, clicked and dismissed notifications.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SignatureHeader holds the hex HMAC-SHA256 of the body, prefixed by
+	// "sha256=".
+	SignatureHeader = "X-GameThrive-Signature"
+	maxBodySize     = 1 << 20
+)
+
+type EventType string
+
+const (
+	Displayed EventType = "notification.displayed"
+	Clicked   EventType = "notification.clicked"
+	Dismissed EventType = "notification.dismissed"
+)
+
+var ErrInvalidSignature = errors.New("Invalid webhook signature")
+
+// Event holds the fields common to every event. NotificationId is the
+// Notification.Id the event refers to. Id is unique to each event and kept
+// when its delivery is retried.
+type Event struct {
+	Id             string    `json:"id,omitempty"`
+	Type           EventType `json:"event"`
+	AppId          string    `json:"app_id"`
+	NotificationId string    `json:"notification_id"`
+	PlayerId       string    `json:"player_id"`
+	Timestamp      int64     `json:"timestamp"`
+}
+
+func (e *Event) Time() time.Time {
+	return time.Unix(e.Timestamp, 0)
+}
+
+type DisplayedEvent struct {
+	Event
+}
+
+type ClickedEvent struct {
+	Event
+	// ActionId is the id of the action button clicked, empty when the
+	// notification itself was clicked.
+	ActionId  string `json:"action_id,omitempty"`
+	LaunchURL string `json:"launch_url,omitempty"`
+}
+
+type DismissedEvent struct {
+	Event
+}
+
+// Sign returns the signature of body with secret, as sent in
+// SignatureHeader.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks signature against the one expected for body.
+func Verify(secret string, body []byte, signature string) error {
+	if !hmac.Equal([]byte(Sign(secret, body)), []byte(strings.TrimSpace(signature))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Decode parses a payload with a single event or a json array of events.
+// It returns *DisplayedEvent, *ClickedEvent and *DismissedEvent values.
+func Decode(data []byte) ([]interface{}, error) {
+	data = bytes.TrimSpace(data)
+	var raws []json.RawMessage
+	if len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &raws); err != nil {
+			return nil, err
+		}
+	} else {
+		raws = []json.RawMessage{data}
+	}
+	events := make([]interface{}, 0, len(raws))
+	for _, raw := range raws {
+		var head Event
+		if err := json.Unmarshal(raw, &head); err != nil {
+			return nil, err
+		}
+		var event interface{}
+		switch head.Type {
+		case Displayed:
+			event = new(DisplayedEvent)
+		case Clicked:
+			event = new(ClickedEvent)
+		case Dismissed:
+			event = new(DismissedEvent)
+		default:
+			return nil, fmt.Errorf("Unknown event type %q", head.Type)
+		}
+		if err := json.Unmarshal(raw, event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// DefaultTolerance is how far the timestamp of an event may be from the
+// receiver clock.
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	ErrNoSecret   = errors.New("Webhook secret is required")
+	ErrStaleEvent = errors.New("Webhook event is outside the accepted time window")
+)
+
+// Receiver is an http.Handler that verifies incoming webhooks and
+// dispatches their events to the registered handlers. Requests with an
+// event whose timestamp is more than Tolerance away from now are rejected,
+// so captured requests cannot be replayed. Handlers run in the request
+// goroutine; if any returns an error the request fails with a 500 so that
+// it is retried. Events are recognized by their Id and Timestamp, and the
+// handlers that already succeeded with an event are not called again for
+// it. Events without an Id are dispatched to every handler each time.
+type Receiver struct {
+	// Secret verifies the signature of requests. A receiver without one
+	// rejects every request.
+	Secret string
+	// Tolerance defaults to DefaultTolerance. Zero or less disables the
+	// check.
+	Tolerance time.Duration
+	Now       func() time.Time
+
+	mu       sync.RWMutex
+	handlers map[EventType][]handler
+	next     int
+
+	seenMu sync.Mutex
+	seen   map[string]*handledEvent
+}
+
+// handler is a registered event handler. Its id stays the same when other
+// handlers are registered.
+type handler struct {
+	id int
+	f  func(event interface{}) error
+}
+
+// handledEvent records the handlers that succeeded with an event.
+type handledEvent struct {
+	at   time.Time
+	done map[int]bool
+}
+
+func NewReceiver(secret string) (*Receiver, error) {
+	if len(secret) <= 0 {
+		return nil, ErrNoSecret
+	}
+	return &Receiver{
+		Secret:    secret,
+		Tolerance: DefaultTolerance,
+		Now:       time.Now,
+	}, nil
+}
+
+func (r *Receiver) OnDisplayed(f func(*DisplayedEvent) error) {
+	r.on(Displayed, func(e interface{}) error { return f(e.(*DisplayedEvent)) })
+}
+
+func (r *Receiver) OnClicked(f func(*ClickedEvent) error) {
+	r.on(Clicked, func(e interface{}) error { return f(e.(*ClickedEvent)) })
+}
+
+func (r *Receiver) OnDismissed(f func(*DismissedEvent) error) {
+	r.on(Dismissed, func(e interface{}) error { return f(e.(*DismissedEvent)) })
+}
+
+func (r *Receiver) on(t EventType, f func(interface{}) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.handlers == nil {
+		r.handlers = map[EventType][]handler{}
+	}
+	r.next++
+	r.handlers[t] = append(r.handlers[t], handler{id: r.next, f: f})
+}
+
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if len(r.Secret) <= 0 {
+		http.Error(w, ErrNoSecret.Error(), http.StatusInternalServerError)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := Verify(r.Secret, body, req.Header.Get(SignatureHeader)); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	events, err := Decode(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	now := r.now()
+	for _, event := range events {
+		if !r.fresh(event, now) {
+			http.Error(w, ErrStaleEvent.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	r.forget(now)
+	for _, event := range events {
+		if err := r.dispatch(event, now); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Dispatch calls the handlers registered for the type of event.
+func (r *Receiver) Dispatch(event interface{}) error {
+	_, handlers, err := r.handlersOf(event)
+	if err != nil {
+		return err
+	}
+	for _, h := range handlers {
+		if err := h.f(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dispatch is Dispatch skipping the handlers that already succeeded with
+// the same event.
+func (r *Receiver) dispatch(event interface{}, now time.Time) error {
+	e, handlers, err := r.handlersOf(event)
+	if err != nil {
+		return err
+	}
+	if len(e.Id) <= 0 {
+		return r.Dispatch(event)
+	}
+	key := fmt.Sprintf("%s/%d", e.Id, e.Timestamp)
+	for _, h := range handlers {
+		r.seenMu.Lock()
+		handled := r.seen[key]
+		done := handled != nil && handled.done[h.id]
+		r.seenMu.Unlock()
+		if done {
+			continue
+		}
+		if err := h.f(event); err != nil {
+			return err
+		}
+		r.seenMu.Lock()
+		if r.seen == nil {
+			r.seen = map[string]*handledEvent{}
+		}
+		if r.seen[key] == nil {
+			r.seen[key] = &handledEvent{done: map[int]bool{}}
+		}
+		r.seen[key].at = now
+		r.seen[key].done[h.id] = true
+		r.seenMu.Unlock()
+	}
+	return nil
+}
+
+// handlersOf returns the common fields of event and the handlers
+// registered for its type.
+func (r *Receiver) handlersOf(event interface{}) (*Event, []handler, error) {
+	var e *Event
+	var t EventType
+	switch ev := event.(type) {
+	case *DisplayedEvent:
+		e, t = &ev.Event, Displayed
+	case *ClickedEvent:
+		e, t = &ev.Event, Clicked
+	case *DismissedEvent:
+		e, t = &ev.Event, Dismissed
+	default:
+		return nil, nil, fmt.Errorf("Unknown event %T", event)
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return e, r.handlers[t], nil
+}
+
+func (r *Receiver) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+// fresh reports whether the timestamp of event is within Tolerance of now.
+func (r *Receiver) fresh(event interface{}, now time.Time) bool {
+	if r.Tolerance <= 0 {
+		return true
+	}
+	e, ok := event.(interface{ Time() time.Time })
+	if !ok {
+		return false
+	}
+	d := now.Sub(e.Time())
+	return d <= r.Tolerance && d >= -r.Tolerance
+}
+
+// forget drops the handled events that are too old to be retried.
+func (r *Receiver) forget(now time.Time) {
+	retention := 2 * r.Tolerance
+	if retention <= 0 {
+		retention = 2 * DefaultTolerance
+	}
+	r.seenMu.Lock()
+	defer r.seenMu.Unlock()
+	for key, handled := range r.seen {
+		if now.Sub(handled.at) > retention {
+			delete(r.seen, key)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReceiver(t *testing.T) {
+	r, err := NewReceiver("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var displayed, dismissed int
+	var clicked *ClickedEvent
+	r.OnDisplayed(func(e *DisplayedEvent) error { displayed++; return nil })
+	r.OnClicked(func(e *ClickedEvent) error { clicked = e; return nil })
+	r.OnDismissed(func(e *DismissedEvent) error { dismissed++; return nil })
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	if err := Post(nil, server.URL, "secret", SampleEvents("app", "n1", "p1")...); err != nil {
+		t.Fatal(err)
+	}
+	if displayed != 1 || dismissed != 1 || clicked == nil {
+		t.Fatalf("displayed = %d, dismissed = %d, clicked = %v", displayed, dismissed, clicked)
+	}
+	if clicked.NotificationId != "n1" || clicked.PlayerId != "p1" || clicked.ActionId != "sample-action" {
+		t.Errorf("clicked = %+v", clicked)
+	}
+
+	if err := Post(nil, server.URL, "wrong", SampleEvents("app", "n1", "p1")[0]); err == nil {
+		t.Error("Expected error for a bad signature")
+	}
+	if err := Post(nil, server.URL, "", SampleEvents("app", "n1", "p1")[0]); err == nil {
+		t.Error("Expected error for an unsigned request")
+	}
+}
+
+func TestReceiver_retry(t *testing.T) {
+	r, _ := NewReceiver("secret")
+	displayed, failures := 0, 1
+	r.OnDisplayed(func(e *DisplayedEvent) error { displayed++; return nil })
+	r.OnDismissed(func(e *DismissedEvent) error {
+		if failures > 0 {
+			failures--
+			return errors.New("boom")
+		}
+		return nil
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+	events := SampleEvents("app", "n1", "p1")
+	if err := Post(nil, server.URL, "secret", events...); err == nil {
+		t.Fatal("Expected error when a handler fails")
+	}
+	if err := Post(nil, server.URL, "secret", events...); err != nil {
+		t.Fatal(err)
+	}
+	if displayed != 1 {
+		t.Errorf("Retried request dispatched the displayed event %d times, want 1", displayed)
+	}
+}
+
+func TestReceiver_sameBody(t *testing.T) {
+	r, _ := NewReceiver("secret")
+	displayed := 0
+	r.OnDisplayed(func(e *DisplayedEvent) error { displayed++; return nil })
+	server := httptest.NewServer(r)
+	defer server.Close()
+	now := time.Now().Unix()
+	events := []interface{}{
+		&DisplayedEvent{Event{Id: "e1", Type: Displayed, NotificationId: "n1", Timestamp: now}},
+		&DisplayedEvent{Event{Id: "e2", Type: Displayed, NotificationId: "n1", Timestamp: now}},
+		&DisplayedEvent{Event{Type: Displayed, NotificationId: "n1", Timestamp: now}},
+		&DisplayedEvent{Event{Type: Displayed, NotificationId: "n1", Timestamp: now}},
+	}
+	for _, event := range events {
+		if err := Post(nil, server.URL, "secret", event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if displayed != 4 {
+		t.Errorf("Dispatched %d of 4 distinct events", displayed)
+	}
+}
+
+func TestReceiver_lateHandler(t *testing.T) {
+	r, _ := NewReceiver("secret")
+	var calls []string
+	r.OnDisplayed(func(e *DisplayedEvent) error { calls = append(calls, "first"); return nil })
+	server := httptest.NewServer(r)
+	defer server.Close()
+	event := SampleEvents("app", "n1", "p1")[0]
+	if err := Post(nil, server.URL, "secret", event); err != nil {
+		t.Fatal(err)
+	}
+	r.OnDisplayed(func(e *DisplayedEvent) error { calls = append(calls, "second"); return nil })
+	if err := Post(nil, server.URL, "secret", event); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"first", "second"}; fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("Handler calls = %v, want %v", calls, want)
+	}
+}
+
+func TestReceiver_replay(t *testing.T) {
+	r, _ := NewReceiver("secret")
+	now := time.Now()
+	r.Now = func() time.Time { return now.Add(10 * time.Minute) }
+	server := httptest.NewServer(r)
+	defer server.Close()
+	if err := Post(nil, server.URL, "secret", SampleEvents("app", "n1", "p1")...); err == nil {
+		t.Error("Expected error for events outside the tolerance")
+	}
+	r.Tolerance = time.Hour
+	if err := Post(nil, server.URL, "secret", SampleEvents("app", "n1", "p1")...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewReceiver_noSecret(t *testing.T) {
+	if _, err := NewReceiver(""); err != ErrNoSecret {
+		t.Errorf("NewReceiver without secret error = %v, want %v", err, ErrNoSecret)
+	}
+	server := httptest.NewServer(new(Receiver))
+	defer server.Close()
+	if err := Post(nil, server.URL, "", SampleEvents("app", "n1", "p1")[0]); err == nil {
+		t.Error("Expected error from a receiver without secret")
+	}
+}
+
+func TestDecode_UnknownType(t *testing.T) {
+	if _, err := Decode([]byte(`{"event":"notification.exploded"}`)); err == nil {
+		t.Error("Expected error for unknown event type")
+	}
+}